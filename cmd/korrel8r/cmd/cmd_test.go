@@ -1,12 +1,19 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/korrel8r/korrel8r/internal/pkg/test"
+	"github.com/korrel8r/korrel8r/pkg/engine"
+	"github.com/korrel8r/korrel8r/pkg/templaterule"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/multierr"
 )
 
 func TestList_Classes(t *testing.T) {
@@ -38,3 +45,103 @@ func TestList_Domains(t *testing.T) {
 	}
 	assert.ElementsMatch(t, want, got)
 }
+
+func TestShow(t *testing.T) {
+	// Does not need a cluster.
+	var exitCode int
+	stdout, stderr := test.FakeMain([]string{"", "show", "testdata/session.json", "--objects", "-o", "json"}, func() {
+		exitCode = Execute()
+	})
+	require.Equal(t, 0, exitCode, stderr)
+	var out struct {
+		Start, Goal string
+		Classes     []struct {
+			Class string
+			Count int
+		}
+	}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &out))
+	assert.Equal(t, "k8s/Pod.v1.", out.Start)
+	assert.Equal(t, "logs/application", out.Goal)
+	require.Len(t, out.Classes, 2)
+	assert.Equal(t, 1, out.Classes[1].Count)
+	assert.Contains(t, stdout, `"Entry":"hello world"`)
+	assert.Contains(t, stdout, `"name":"hello"`)
+}
+
+func TestDiff(t *testing.T) {
+	// Does not need a cluster.
+	b, err := os.ReadFile("testdata/session.json")
+	require.NoError(t, err)
+	after := filepath.Join(t.TempDir(), "after.json")
+	require.NoError(t, os.WriteFile(after, bytes.ReplaceAll(b, []byte("hello world"), []byte("goodbye")), 0600))
+	var exitCode int
+	stdout, stderr := test.FakeMain([]string{"", "diff", "testdata/session.json", after, "--objects", "-o", "json"}, func() {
+		exitCode = Execute()
+	})
+	require.Equal(t, 0, exitCode, stderr)
+	assert.JSONEq(t, `{
+  "nodes": [{"class": "logs/application", "change": "changed", "before": 1, "after": 1, "added": [{"Entry": "goodbye"}], "removed": [{"Entry": "hello world"}]}],
+  "lines": []
+}`, stdout)
+}
+
+func TestLintRules(t *testing.T) {
+	// Does not need a cluster.
+	e := engine.New()
+	addDomains(e)
+	problems := lintRules(e, []string{"testdata/lint", "testdata/nosuchfile"})
+	require.Len(t, problems, 2, "%v", problems)
+	assert.Equal(t, templaterule.GroupProblem, problems[0].Kind)
+	assert.Equal(t, "WorkloadToLogs", problems[0].Rule)
+	assert.Contains(t, problems[0].Msg, "group workloads has unknown class NoSuchKind")
+	assert.Equal(t, loadProblem, problems[1].Kind)
+	assert.Contains(t, problems[1].Msg, "nosuchfile")
+}
+
+func TestRulesLint(t *testing.T) {
+	// Does not need a cluster.
+	// PodDisruptionBudget is only used as a start class, so it is not reachable from alert.
+	var exitCode int
+	stdout, stderr := test.FakeMain([]string{"", "rules", "lint", "--ignore", "unreachable", "-o", "json"}, func() {
+		exitCode = Execute()
+	})
+	require.Equal(t, 0, exitCode, stderr)
+	var problems engine.Problems
+	require.NoError(t, json.Unmarshal([]byte(stdout), &problems))
+	assert.NotEmpty(t, problems, "expected cycle warnings")
+	for _, p := range problems {
+		assert.True(t, p.Kind.Warning(), "%v", p)
+	}
+}
+
+func TestLoadRules_Errors(t *testing.T) {
+	// Does not need a cluster.
+	e := engine.New()
+	addDomains(e)
+	err := loadRules(e, "testdata/badrules")
+	require.Error(t, err)
+	var got []string
+	for _, err := range multierr.Errors(err) {
+		got = append(got, err.Error())
+	}
+	require.Len(t, got, 2, "%v", err)
+	assert.Contains(t, got[0], "testdata/badrules/rules.yaml:4:7: rule BadClass: start:")
+	assert.Contains(t, got[1], "testdata/badrules/rules.yaml:18:9: rule BadTemplate: result.query:")
+}
+
+func TestRulesTest(t *testing.T) {
+	// Does not need a cluster.
+	var exitCode int
+	stdout, stderr := test.FakeMain([]string{"", "rules", "test", "testdata/ruletests", "-o", "json"}, func() {
+		exitCode = Execute()
+	})
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, stderr, "1 of 2 rule tests failed")
+	var results []ruleTestResult
+	require.NoError(t, json.Unmarshal([]byte(stdout), &results))
+	require.Len(t, results, 2)
+	assert.Equal(t, ruleTestResult{File: "testdata/ruletests/tests.yaml", Test: "pass"}, results[0])
+	assert.Equal(t, "fail", results[1].Test)
+	assert.Contains(t, results[1].Error, "expected query")
+}
@@ -6,12 +6,14 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
-	"net/url"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/korrel8r/korrel8r/internal/pkg/logging"
 	"github.com/korrel8r/korrel8r/internal/pkg/must"
+	"github.com/korrel8r/korrel8r/pkg/config"
+	"github.com/korrel8r/korrel8r/pkg/domains"
 	"github.com/korrel8r/korrel8r/pkg/domains/alert"
 	"github.com/korrel8r/korrel8r/pkg/domains/k8s"
 	"github.com/korrel8r/korrel8r/pkg/domains/logs"
@@ -23,7 +25,7 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/util/flowcontrol"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	k8sconfig "sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/yaml"
 )
 
@@ -33,7 +35,7 @@ var (
 )
 
 func restConfig() *rest.Config {
-	cfg := must.Must1(config.GetConfig())
+	cfg := must.Must1(k8sconfig.GetConfig())
 	cfg.RateLimiter = flowcontrol.NewTokenBucketRateLimiter(100, 1000)
 	return cfg
 }
@@ -43,85 +45,157 @@ func k8sClient(cfg *rest.Config) client.Client {
 	return must.Must1(client.New(cfg, client.Options{}))
 }
 
-func parseURL(s string) (*url.URL, error) {
-	u, err := url.Parse(s)
-	if err != nil {
-		return nil, err
+// engineConfig returns the configuration from --config, or from command line flags if there is no --config.
+func engineConfig() *config.Config {
+	if *configFile != "" {
+		log.V(1).Info("loading configuration", "file", *configFile)
+		c := must.Must1(config.Load(*configFile))
+		if len(c.Rules) == 0 || rootCmd.PersistentFlags().Changed("rules") {
+			c.Rules = append(c.Rules, *rulePaths...)
+		}
+		return c
+	}
+	alertStore := config.Store{Domain: alert.Domain.String()}
+	// Without --alertmanager-url, the alert store is found from the cluster even if --metrics-url is set.
+	if *alertmanagerAPI != "" {
+		alertStore.Kind = alert.KindAlertmanager
+		alertStore.URL = *alertmanagerAPI
+		alertStore.Params = map[string]string{alert.PrometheusURLParam: *metricsAPI}
 	}
-	if u.Scheme != "http" && u.Scheme != "https" {
-		return nil, fmt.Errorf("%q: unsupported scheme", s)
+	return &config.Config{
+		Rules: *rulePaths,
+		Stores: []config.Store{
+			{Domain: k8s.Domain.String()},
+			alertStore,
+			{Domain: logs.Domain.String(), URL: *logsAPI},
+			{Domain: metric.Domain.String(), URL: *metricsAPI},
+		},
 	}
-	return u, nil
 }
 
 func newEngine() *engine.Engine {
+	e, c := newStoreEngine()
+	addRules(e, c)
+	return e
+}
+
+// newStoreEngine creates an engine with domains and stores but no rules.
+// Returns the configuration, which includes the rule paths.
+func newStoreEngine() (*engine.Engine, *config.Config) {
 	log.V(2).Info("create engine")
 	cfg := restConfig()
 	e := engine.New()
-	for _, x := range []struct {
-		d      korrel8r.Domain
-		create func() (korrel8r.Store, error)
-	}{
-		{k8s.Domain, func() (korrel8r.Store, error) { return k8s.NewStore(k8sClient(cfg), cfg) }},
-		{alert.Domain, func() (korrel8r.Store, error) {
-			if *alertmanagerAPI == "" && *metricsAPI == "" {
-				return alert.NewOpenshiftStore(ctx, cfg)
+	addDomains(e)
+	env := domains.StoreEnv{Context: ctx, RESTConfig: cfg}
+	c := engineConfig()
+	for _, sc := range c.Stores {
+		id := engine.StoreID(sc.Domain, sc.Name)
+		s, err := domains.NewStore(env, sc)
+		if err != nil {
+			log.Error(err, "error creating store", "store", id)
+			continue
+		}
+		if err := e.AddStore(sc.Domain, sc.Name, s); err != nil {
+			log.Error(err, "invalid store configuration")
+			continue
+		}
+		must.Must(e.SetStoreConcurrency(id, sc.Concurrency))
+		must.Must(e.SetStoreTimeout(id, sc.Timeout.Duration))
+		if sc.Cache != nil {
+			must.Must(e.SetStoreCache(id, sc.Cache.TTL.Duration, sc.Cache.Size))
+		}
+	}
+	// Flags override the configuration, they apply to all stores for a domain, or to a single 'domain/name' store.
+	if *configFile == "" || rootCmd.PersistentFlags().Changed("concurrency") {
+		for id, n := range *concurrency {
+			if err := e.SetStoreConcurrency(id, n); err != nil {
+				log.V(1).Info("cannot limit store concurrency", "error", err.Error())
 			}
+		}
+	}
+	for id, d := range *storeTimeout {
+		if err := e.SetStoreTimeout(id, must.Must1(time.ParseDuration(d))); err != nil {
+			log.Error(err, "cannot set store timeout", "store", id)
+		}
+	}
+	for id, ttl := range *cacheTTL {
+		d := must.Must1(time.ParseDuration(ttl))
+		if err := e.SetStoreCache(id, d, *cacheSize); err != nil {
+			log.Error(err, "cannot cache store", "store", id)
+		}
+	}
 
-			log.V(1).Info("using user-specified Alertmanager API", "url", *alertmanagerAPI)
-			alertmanagerURL, err := parseURL(*alertmanagerAPI)
-			if err != nil {
-				return nil, err
-			}
+	if *ruleStats != "" {
+		e.SetStats(must.Must1(engine.LoadStats(*ruleStats)))
+	}
+	return e, c
+}
 
-			log.V(1).Info("using user-specified metrics API", "url", *metricsAPI)
-			prometheusURL, err := parseURL(*metricsAPI)
-			if err != nil {
-				return nil, err
-			}
+// newOfflineEngine creates an engine with domains and rules but no stores.
+// It does not connect to a cluster, it is used to work with saved correlations.
+func newOfflineEngine() *engine.Engine {
+	log.V(2).Info("create offline engine")
+	e := engine.New()
+	addDomains(e)
+	addRules(e, engineConfig())
+	return e
+}
 
-			return alert.NewStore(alertmanagerURL, prometheusURL, nil)
-		}},
-		{logs.Domain, func() (korrel8r.Store, error) {
-			if *logsAPI == "" {
-				return logs.NewOpenshiftLokiStackStore(ctx, k8sClient(cfg), cfg)
-			}
+// newLintEngine creates an engine with domains and configured stores but no rules, for linting.
+// It does not connect to a cluster, the stores are placeholders that cannot be queried.
+func newLintEngine() (*engine.Engine, *config.Config) {
+	log.V(2).Info("create lint engine")
+	e := engine.New()
+	addDomains(e)
+	c := engineConfig()
+	for _, sc := range c.Stores {
+		d, err := e.DomainErr(sc.Domain)
+		if err == nil {
+			err = e.AddStore(sc.Domain, sc.Name, configStore{domain: d})
+		}
+		if err != nil {
+			log.Error(err, "invalid store configuration")
+		}
+	}
+	if *ruleStats != "" {
+		e.SetStats(must.Must1(engine.LoadStats(*ruleStats)))
+	}
+	return e, c
+}
 
-			log.V(1).Info("using user-specified logs API", "url", *logsAPI)
-			u, err := parseURL(*logsAPI)
-			if err != nil {
-				return nil, err
-			}
+// configStore is a placeholder for a configured store that is not connected.
+type configStore struct{ domain korrel8r.Domain }
 
-			return logs.NewLokiStackStore(u, nil)
-		}},
-		{metric.Domain, func() (korrel8r.Store, error) {
-			if *metricsAPI != "" {
-				log.V(1).Info("using user-specified metrics API", "url", *metricsAPI)
-				u, err := parseURL(*metricsAPI)
-				if err != nil {
-					return nil, err
-				}
-
-				return metric.NewStore(u, nil)
-			}
+func (s configStore) Domain() korrel8r.Domain { return s.domain }
+func (s configStore) Get(context.Context, korrel8r.Query, *korrel8r.Constraint, korrel8r.Appender) error {
+	return fmt.Errorf("store not connected: %v", s.domain)
+}
 
-			return metric.NewOpenshiftStore(ctx, k8sClient(cfg), cfg)
-		}},
-	} {
-		log.V(3).Info("add domain", "domain", x.d)
-		s, err := x.create()
-		if err != nil {
-			log.Error(err, "error creating store", "domain", x.d)
-		}
-		e.AddDomain(x.d, s)
+func addDomains(e *engine.Engine) {
+	for _, p := range domains.List() {
+		log.V(3).Info("add domain", "domain", p.Domain)
+		e.AddDomain(p.Domain, nil)
 	}
+}
 
-	// Load rules
-	for _, path := range *rulePaths {
+func addRules(e *engine.Engine, c *config.Config) {
+	for _, path := range c.Rules {
 		must.Must(loadRules(e, path))
 	}
-	return e
+}
+
+// limits returns the correlation limits from command line flags.
+func limits() engine.Limits {
+	return engine.Limits{MaxObjects: *maxObjects, MaxLineQueries: *maxLineQueries, MaxQueries: *maxQueries}
+}
+
+// saveStats saves rule statistics if --rule-stats is set.
+func saveStats(e *engine.Engine) {
+	if *ruleStats != "" && e.Stats() != nil {
+		if err := e.Stats().Save(*ruleStats); err != nil {
+			log.Error(err, "cannot save rule statistics")
+		}
+	}
 }
 
 // printer prints in the format requested by --output
@@ -161,6 +235,13 @@ func (p printer) Append(objects ...korrel8r.Object) {
 
 // loadRules from a file or walk a directory to find files.
 func loadRules(e *engine.Engine, root string) error {
+	return walkRules(root, func(path string, r io.Reader) error {
+		return templaterule.DecodeFile(path, r, e)
+	})
+}
+
+// walkRules calls load for a rule file, or for each rule file in a directory.
+func walkRules(root string, load func(path string, r io.Reader) error) error {
 	log.V(2).Info("loading rules from", "root", root)
 	return filepath.WalkDir(root, func(path string, info fs.DirEntry, err error) error {
 		if err != nil {
@@ -176,9 +257,6 @@ func loadRules(e *engine.Engine, root string) error {
 			return err
 		}
 		defer f.Close()
-		if err := templaterule.Decode(f, e); err != nil {
-			return fmt.Errorf("%v:0 error loading rules: %v", path, err)
-		}
-		return nil
+		return load(path, f)
 	})
 }
@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/korrel8r/korrel8r/internal/pkg/must"
+	"github.com/korrel8r/korrel8r/pkg/engine"
+	"github.com/korrel8r/korrel8r/pkg/graph"
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+	"github.com/korrel8r/korrel8r/pkg/openshift/console"
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
+)
+
+var correlateCmd = &cobra.Command{
+	Use:   "correlate --start QUERY|CONSOLE-URL [--goal DOMAIN/CLASS | --neighbours N [--reverse]]",
+	Short: "Correlate from a start query or console URL, print the classes and queries reached.",
+	Long: `
+Start from the results of a query (requires --domain) or an OpenShift console URL.
+With --goal, follow rules along paths from the start class to the goal class.
+Otherwise follow rules to find all classes within --neighbours steps of the start class.
+With --reverse, find the upstream classes that have rules leading to the start class within --neighbours steps,
+and follow inverse rules from the start objects to find upstream objects.
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		e := newEngine()
+		r := engine.Request{
+			Depth:         *correlateNeighbours,
+			Reverse:       *correlateReverse,
+			ShortestPaths: *correlateShortest,
+			KShortest:     *correlateKShortest,
+			MaxPathLength: *correlateMaxPathLength,
+			MaxPaths:      *correlateMaxPaths,
+			RulesOnly:     *correlateRulesOnly,
+			Constraint:    correlateConstraint(),
+			Timeout:       *timeout,
+			Limits:        limits(),
+			Stores:        *correlateStores,
+		}
+		r.Queries = []korrel8r.Query{must.Must1(startQuery(e, *correlateStart, *correlateDomain))}
+		if *correlateGoal != "" {
+			r.Goal = must.Must1(e.Class(*correlateGoal))
+		}
+		c := must.Must1(e.Correlate(ctx, r))
+		saveStats(e)
+		if *correlateSave != "" {
+			must.Must(saveCorrelation(c, *correlateSave))
+		}
+		log.V(1).Info("correlation complete", "cache", e.CacheStats())
+		if err := c.Failures.Kind(engine.StoreFailed, engine.StoreTimeout).Err(); err != nil {
+			log.Error(err, "correlation may be incomplete")
+		}
+		if *correlateDiff != "" {
+			before := must.Must1(loadCorrelation(e, *correlateDiff))
+			printDiff(engine.NewDiff(before, c), *correlateObjects, false)
+			return
+		}
+		if *correlateGraph != "" {
+			must.Must(c.Graph.Encode(os.Stdout, *correlateGraph))
+			return
+		}
+		out := newCorrelateOutput(c, *correlateObjects)
+		if *correlateProvenance {
+			out.addProvenance(c)
+		}
+		newPrinter(os.Stdout).Print(out)
+	},
+}
+
+var (
+	correlateStart, correlateDomain, correlateGoal                *string
+	correlateGraph, correlateSave, correlateDiff                  *string
+	correlateNeighbours                                           *int
+	correlateKShortest, correlateMaxPathLength, correlateMaxPaths *int
+	correlateShortest, correlateRulesOnly, correlateObjects       *bool
+	correlateReverse, correlateProvenance                         *bool
+	correlateConstraint                                           func() *korrel8r.Constraint
+	correlateStores                                               *map[string]string
+)
+
+func init() {
+	rootCmd.AddCommand(correlateCmd)
+	correlateStart = correlateCmd.Flags().String("start", "", "Start query, or OpenShift console URL")
+	correlateDomain = correlateCmd.Flags().String("domain", "", "Domain of the start query, not needed for a console URL")
+	correlateGoal = correlateCmd.Flags().String("goal", "", "Goal class as DOMAIN/CLASS, if not set correlate neighbours")
+	correlateNeighbours = correlateCmd.Flags().Int("neighbours", 3, "Depth of neighbourhood search, if --goal is not set")
+	correlateShortest = correlateCmd.Flags().Bool("shortest", false, "Follow only the cheapest paths to the goal, preferring rules that returned results in the past. See --rule-stats")
+	correlateKShortest = correlateCmd.Flags().Int("k-shortest", 0, "Follow only the K cheapest paths to the goal, if > 0")
+	correlateMaxPathLength = correlateCmd.Flags().Int("max-path-length", 0, "Max number of rules on a path to the goal, 0 means no limit")
+	correlateMaxPaths = correlateCmd.Flags().Int("max-paths", 0, "Max number of paths to the goal, 0 means no limit")
+	correlateReverse = correlateCmd.Flags().Bool("reverse", false, "Find upstream classes with rules leading to the start, instead of following rules from the start")
+	correlateRulesOnly = correlateCmd.Flags().Bool("rules-only", false, "Show the rule graph without getting results")
+	correlateObjects = correlateCmd.Flags().Bool("objects", false, "Include result objects in the output")
+	correlateProvenance = correlateCmd.Flags().Bool("provenance", false, "Include each result object with the chains of rules and queries that found it")
+	correlateGraph = correlateCmd.Flags().String("graph", "", fmt.Sprintf("Print the correlation graph instead of a list of classes, format is one of %v", graph.Formats))
+	correlateSave = correlateCmd.Flags().String("save", "", "Save the correlation with its results to a file, see the 'show' command")
+	correlateDiff = correlateCmd.Flags().String("diff", "", "Compare with a correlation saved by --save, print what changed")
+	correlateConstraint = addConstraintFlags(correlateCmd)
+	correlateStores = correlateCmd.Flags().StringToString("store", nil, "Use only the named store for a domain, as domain=name. Other domains use all their stores.")
+	must.Must(correlateCmd.MarkFlagRequired("start"))
+}
+
+// saveCorrelation saves c to a file.
+func saveCorrelation(c *engine.Correlation, file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := c.Save(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// startQuery parses a query for domain, or a console URL if domain is empty.
+func startQuery(e *engine.Engine, start, domain string) (korrel8r.Query, error) {
+	if domain != "" {
+		d, err := e.DomainErr(domain)
+		if err != nil {
+			return nil, err
+		}
+		return d.UnmarshalQuery([]byte(start))
+	}
+	u, err := url.Parse(start)
+	if err != nil {
+		return nil, err
+	}
+	if u.Path == "" {
+		return nil, errors.New("start is not a console URL, use --domain for a query")
+	}
+	// Only the URL path and query are used, no need for the console base URL.
+	return console.New(nil, e).ConsoleURLToQuery(u)
+}
+
+// correlateOutput is the printed form of an engine.Correlation.
+type correlateOutput struct {
+	Start    string          `json:"start"`
+	Goal     string          `json:"goal,omitempty"`
+	Classes  []classOutput   `json:"classes"`
+	Upstream []string        `json:"upstream,omitempty"` // Classes leading to start, for --reverse.
+	Failures engine.Failures `json:"failures,omitempty"`
+}
+
+type classOutput struct {
+	Class      string             `json:"class"`
+	Count      int                `json:"count"`
+	Incomplete bool               `json:"incomplete,omitempty"` // Some queries failed or timed out.
+	Truncated  bool               `json:"truncated,omitempty"`  // Some objects or queries were dropped by a limit.
+	Queries    []graph.QueryCount `json:"queries,omitempty"`
+	Objects    []korrel8r.Object  `json:"objects,omitempty"`
+	Provenance []objectProvenance `json:"provenance,omitempty"`
+}
+
+// objectProvenance is an object with the chains of steps that found it, see graph.Node.Provenance.
+type objectProvenance struct {
+	Object korrel8r.Object `json:"object"`
+	Chains [][]stepOutput  `json:"chains"`
+}
+
+type stepOutput struct {
+	Class  string          `json:"class"`
+	Rule   string          `json:"rule,omitempty"`  // Rule applied to the previous object, empty for a start object.
+	Query  korrel8r.Query  `json:"query,omitempty"` // Query that returned the object.
+	Object korrel8r.Object `json:"object"`
+}
+
+func newCorrelateOutput(c *engine.Correlation, objects bool) *correlateOutput {
+	out := &correlateOutput{Start: korrel8r.ClassName(c.Start), Failures: c.Failures}
+	if c.Goal != nil {
+		out.Goal = korrel8r.ClassName(c.Goal)
+	}
+	for _, n := range c.Nodes() {
+		co := classOutput{Class: korrel8r.ClassName(n.Class), Count: len(n.Objects), Incomplete: n.Incomplete, Truncated: n.Truncated, Queries: n.Queries}
+		if objects {
+			co.Objects = n.Objects
+		}
+		out.Classes = append(out.Classes, co)
+	}
+	if c.Reverse {
+		for _, n := range c.Graph.AllNodes() {
+			if n.Class != c.Start {
+				out.Upstream = append(out.Upstream, korrel8r.ClassName(n.Class))
+			}
+		}
+		slices.Sort(out.Upstream)
+	}
+	return out
+}
+
+// addProvenance adds the provenance of each object to the output.
+func (out *correlateOutput) addProvenance(c *engine.Correlation) {
+	for i, nr := range c.Nodes() { // Same order as out.Classes
+		co := &out.Classes[i]
+		n := c.Graph.NodeFor(nr.Class)
+		for _, o := range n.Result.List() {
+			op := objectProvenance{Object: o}
+			for _, chain := range n.Provenance(o) {
+				var steps []stepOutput
+				for _, s := range chain {
+					so := stepOutput{Class: korrel8r.ClassName(s.Class), Query: s.Query, Object: s.Object}
+					if s.Rule != nil {
+						so.Rule = s.Rule.String()
+					}
+					steps = append(steps, so)
+				}
+				op.Chains = append(op.Chains, steps)
+			}
+			co.Provenance = append(co.Provenance, op)
+		}
+	}
+}
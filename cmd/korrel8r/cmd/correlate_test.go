@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/korrel8r/korrel8r/internal/pkg/test/mock"
+	"github.com/korrel8r/korrel8r/pkg/engine"
+	"github.com/korrel8r/korrel8r/pkg/graph"
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCorrelateOutput(t *testing.T) {
+	s := mock.Store{}
+	e := engine.New()
+	e.AddDomain(mock.Domain("mock"), s)
+	e.AddRules(mock.NewRule("ab", "mock/a", "mock/b", func(korrel8r.Object, *korrel8r.Constraint) (korrel8r.Query, error) {
+		return s.NewQuery("mock/b:1", "mock/b:2"), nil
+	}))
+	c, err := e.Correlate(context.Background(), engine.Request{
+		Queries: []korrel8r.Query{s.NewQuery("mock/a:1")},
+		Goal:    mock.Class("mock/b"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &correlateOutput{
+		Start: "mock/a",
+		Goal:  "mock/b",
+		Classes: []classOutput{
+			{Class: "mock/a", Count: 1, Queries: []graph.QueryCount{{Query: mock.Query("mock/a:1"), Count: 1, Stores: map[string]int{"mock": 1}}},
+				Objects: mock.Objects("mock/a:1")},
+			{Class: "mock/b", Count: 2, Queries: []graph.QueryCount{{Query: mock.Query("mock/b:1&mock/b:2"), Count: 2, Stores: map[string]int{"mock": 2}}},
+				Objects: mock.Objects("mock/b:1", "mock/b:2")},
+		},
+	}, newCorrelateOutput(c, true))
+}
+
+func TestNewCorrelateOutput_Reverse(t *testing.T) {
+	e := engine.New()
+	e.AddDomain(mock.Domain("mock"), mock.Store{})
+	e.AddRules(mock.QuickRule("mock/a", "mock/b"), mock.QuickRule("mock/c", "mock/b"))
+	c, err := e.Correlate(context.Background(), engine.Request{Start: mock.Class("mock/b"), Depth: 1, Reverse: true, RulesOnly: true})
+	require.NoError(t, err)
+	out := newCorrelateOutput(c, false)
+	assert.Equal(t, []string{"mock/a", "mock/c"}, out.Upstream)
+}
+
+func TestCorrelateOutput_Provenance(t *testing.T) {
+	s := mock.Store{}
+	e := engine.New()
+	e.AddDomain(mock.Domain("mock"), s)
+	e.AddRules(mock.NewRule("ab", "mock/a", "mock/b", func(korrel8r.Object, *korrel8r.Constraint) (korrel8r.Query, error) {
+		return s.NewQuery("mock/b:1"), nil
+	}))
+	c, err := e.Correlate(context.Background(), engine.Request{Start: mock.Class("mock/a"), Objects: mock.Objects("mock/a:1"), Goal: mock.Class("mock/b")})
+	require.NoError(t, err)
+	out := newCorrelateOutput(c, false)
+	out.addProvenance(c)
+	assert.Equal(t, []objectProvenance{{
+		Object: mock.Object("mock/b:1"),
+		Chains: [][]stepOutput{{
+			{Class: "mock/a", Object: mock.Object("mock/a:1")},
+			{Class: "mock/b", Rule: "ab", Query: mock.Query("mock/b:1"), Object: mock.Object("mock/b:1")},
+		}},
+	}}, out.Classes[1].Provenance)
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/korrel8r/korrel8r/internal/pkg/must"
+	"github.com/korrel8r/korrel8r/pkg/engine"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff BEFORE AFTER",
+	Short: "Compare two correlations saved by 'correlate --save', print classes and rules that changed.",
+	Long: `
+Compare two saved correlations, for example from before and after a fix.
+Use 'correlate --diff' to compare a saved correlation with a live one.
+`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		e := newOfflineEngine()
+		before := must.Must1(loadCorrelation(e, args[0]))
+		after := must.Must1(loadCorrelation(e, args[1]))
+		printDiff(engine.NewDiff(before, after), *diffObjects, *diffAll)
+	},
+}
+
+var diffObjects, diffAll *bool
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffObjects = diffCmd.Flags().Bool("objects", false, "Include added and removed objects in the output")
+	diffAll = diffCmd.Flags().Bool("all", false, "Include unchanged classes and rules in the output")
+}
+
+// loadCorrelation loads a correlation from a file.
+func loadCorrelation(e *engine.Engine, file string) (*engine.Correlation, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return e.LoadCorrelation(f)
+}
+
+// printDiff prints a diff, omitting objects and unchanged entries unless requested.
+func printDiff(d *engine.Diff, objects, all bool) {
+	out := engine.Diff{Nodes: []engine.NodeDiff{}, Lines: []engine.LineDiff{}}
+	for _, nd := range d.Nodes {
+		if all || nd.Change != engine.Unchanged {
+			if !objects {
+				nd.Added, nd.Removed = nil, nil
+			}
+			out.Nodes = append(out.Nodes, nd)
+		}
+	}
+	for _, ld := range d.Lines {
+		if all || ld.Change != engine.Unchanged {
+			out.Lines = append(out.Lines, ld)
+		}
+	}
+	newPrinter(os.Stdout).Print(out)
+}
@@ -0,0 +1,10 @@
+package cmd
+
+// Domains included in the korrel8r command.
+// A custom build can include other domains by importing their packages here, see package domains.
+import (
+	_ "github.com/korrel8r/korrel8r/pkg/domains/alert"
+	_ "github.com/korrel8r/korrel8r/pkg/domains/k8s"
+	_ "github.com/korrel8r/korrel8r/pkg/domains/logs"
+	_ "github.com/korrel8r/korrel8r/pkg/domains/metric"
+)
@@ -5,13 +5,16 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+	"github.com/spf13/cobra"
 )
 
 // TimeFlag for flags
 type TimeFlag struct{ Time *time.Time }
 
 func (f *TimeFlag) String() string {
-	if f.Time != nil {
+	if f.Time != nil && !f.Time.IsZero() {
 		return f.Time.String()
 	}
 	return ""
@@ -41,6 +44,33 @@ func (f *URLFlag) Set(s string) error {
 
 func (URLFlag) Type() string { return "URL" }
 
+// addConstraintFlags adds flags to cmd, returns a function to get the resulting constraint.
+func addConstraintFlags(cmd *cobra.Command) func() *korrel8r.Constraint {
+	var (
+		start, end time.Time
+		limit      uint
+	)
+	cmd.Flags().Var(&TimeFlag{Time: &start}, "since", "Include only results timestamped after this time (RFC3339)")
+	cmd.Flags().Var(&TimeFlag{Time: &end}, "until", "Include only results timestamped before this time (RFC3339)")
+	cmd.Flags().UintVar(&limit, "limit", 0, "Max number of results per query, 0 for no limit")
+	return func() *korrel8r.Constraint {
+		if start.IsZero() && end.IsZero() && limit == 0 {
+			return nil
+		}
+		c := &korrel8r.Constraint{}
+		if !start.IsZero() {
+			c.Start = &start
+		}
+		if !end.IsZero() {
+			c.End = &end
+		}
+		if limit != 0 {
+			c.Limit = &limit
+		}
+		return c
+	}
+}
+
 type EnumFlag struct {
 	Value *string
 	Enum  []string
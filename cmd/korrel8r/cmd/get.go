@@ -15,7 +15,7 @@ import (
 // getCmd represents the get command
 var getCmd = &cobra.Command{
 	Use:   "get DOMAIN QUERY",
-	Short: "Execute QUERY in DOMAIN and print the results",
+	Short: "Execute QUERY in all stores for DOMAIN and print the results",
 	Long: `
 `,
 	Args: cobra.ExactArgs(2),
@@ -23,14 +23,16 @@ var getCmd = &cobra.Command{
 		e := newEngine()
 		d := must.Must1(e.DomainErr(args[0]))
 		q := must.Must1(d.UnmarshalQuery([]byte(args[1])))
-		s := must.Must1(e.StoreErr(d.String()))
 
 		log.V(3).Info("get", "query", q, "class", korrel8r.ClassName(q.Class()))
 		result := newPrinter(os.Stdout)
-		must.Must(s.Get(context.Background(), q, result))
+		must.Must(e.Get(context.Background(), q.Class(), q, getConstraint(), result))
 	},
 }
 
+var getConstraint func() *korrel8r.Constraint
+
 func init() {
 	rootCmd.AddCommand(getCmd)
+	getConstraint = addConstraintFlags(getCmd)
 }
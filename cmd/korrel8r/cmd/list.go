@@ -33,7 +33,7 @@ var listCmd = &cobra.Command{
 	},
 }
 
-var rulesCmd = &cobra.Command{
+var listRulesCmd = &cobra.Command{
 	Use:   "rules",
 	Short: "List rules by start, goal or name",
 	Run: func(cmd *cobra.Command, args []string) {
@@ -62,9 +62,9 @@ var rulesCmd = &cobra.Command{
 var ruleStart, ruleGoal, ruleName *string
 
 func init() {
-	ruleStart = rulesCmd.Flags().String("start", "", "show rules with this start class")
-	ruleGoal = rulesCmd.Flags().String("goal", "", "show rules with this goal class")
-	ruleName = rulesCmd.Flags().String("name", "", "show rules with name matching this regexp")
+	ruleStart = listRulesCmd.Flags().String("start", "", "show rules with this start class")
+	ruleGoal = listRulesCmd.Flags().String("goal", "", "show rules with this goal class")
+	ruleName = listRulesCmd.Flags().String("name", "", "show rules with name matching this regexp")
 	rootCmd.AddCommand(listCmd)
-	listCmd.AddCommand(rulesCmd)
+	listCmd.AddCommand(listRulesCmd)
 }
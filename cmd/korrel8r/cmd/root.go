@@ -21,6 +21,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/korrel8r/korrel8r/internal/pkg/logging"
 	"github.com/korrel8r/korrel8r/internal/pkg/must"
@@ -41,6 +42,16 @@ var (
 	alertmanagerAPI *string
 	logsAPI         *string
 	panicOnErr      *bool
+	concurrency     *map[string]int
+	cacheTTL        *map[string]string
+	cacheSize       *int
+	storeTimeout    *map[string]string
+	timeout         *time.Duration
+	configFile      *string
+	ruleStats       *string
+	maxObjects      *int
+	maxLineQueries  *int
+	maxQueries      *int
 )
 
 func init() {
@@ -49,8 +60,18 @@ func init() {
 	verbose = rootCmd.PersistentFlags().IntP("verbose", "v", 0, "Verbosity for logging")
 	rulePaths = rootCmd.PersistentFlags().StringArray("rules", defaultRulePaths(), "Files or directories containing rules.")
 	metricsAPI = rootCmd.PersistentFlags().StringP("metrics-url", "", "", "URL to the metrics API")
-	alertmanagerAPI = rootCmd.PersistentFlags().StringP("alertmanager-url", "", "", "URL to the Alertmanager API")
+	alertmanagerAPI = rootCmd.PersistentFlags().StringP("alertmanager-url", "", "", "URL to the Alertmanager API, requires --metrics-url")
 	logsAPI = rootCmd.PersistentFlags().StringP("logs-url", "", "", "URL to the logs API")
+	concurrency = rootCmd.PersistentFlags().StringToInt("concurrency", map[string]int{"logs": 8}, "Max concurrent requests to each store for a domain, as domain=n or domain/name=n. 0 means no limit.")
+	cacheTTL = rootCmd.PersistentFlags().StringToString("cache", nil, "Cache store results for a domain, as domain=TTL or domain/name=TTL. A TTL of 0 means no expiry.")
+	cacheSize = rootCmd.PersistentFlags().Int("cache-size", 1000, "Max number of cached query results per store, 0 means no limit.")
+	storeTimeout = rootCmd.PersistentFlags().StringToString("store-timeout", nil, "Timeout for each request to the stores for a domain, as domain=duration or domain/name=duration.")
+	timeout = rootCmd.PersistentFlags().Duration("timeout", 0, "Timeout for a correlation, 0 means no timeout. Partial results are returned on timeout.")
+	configFile = rootCmd.PersistentFlags().String("config", "", "Configuration file for stores and rules, replaces the store URL flags.")
+	ruleStats = rootCmd.PersistentFlags().String("rule-stats", "", "File to load and save rule statistics, used to prefer productive rules for shortest paths.")
+	maxObjects = rootCmd.PersistentFlags().Int("max-objects", 0, "Max objects collected for each class in a correlation, 0 means no limit.")
+	maxLineQueries = rootCmd.PersistentFlags().Int("max-rule-queries", 0, "Max queries generated by each rule in a correlation, 0 means no limit.")
+	maxQueries = rootCmd.PersistentFlags().Int("max-queries", 0, "Max queries for a correlation, 0 means no limit.")
 	cobra.OnInitialize(func() { logging.Init(*verbose) })
 }
 
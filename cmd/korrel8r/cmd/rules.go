@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/korrel8r/korrel8r/internal/pkg/must"
+	"github.com/korrel8r/korrel8r/pkg/engine"
+	"github.com/korrel8r/korrel8r/pkg/templaterule"
+	"github.com/spf13/cobra"
+	"go.uber.org/multierr"
+	"golang.org/x/exp/slices"
+)
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Check and test rules. To list rules see 'list rules'",
+}
+
+var rulesLintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Report problems with the rules, exit with an error if there are any.",
+	Long: `
+Load the rules from --rules or --config and report:
+- rules whose goal domain has no store in the configuration,
+- classes that cannot be reached from any class in the --from domains,
+- rules that never generated a query, if --rule-stats is set,
+- rules with the same start, goal and template,
+- cycles between classes, as a warning that does not cause an error exit,
+- rules using groups that expand to unknown classes.
+
+Lint does not connect to a cluster or to any store.
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		e, c := newLintEngine()
+		problems := lintRules(e, c.Rules)
+		problems = append(problems, e.Lint(*lintFrom...)...)
+		var report engine.Problems
+		for _, p := range problems {
+			if !slices.Contains(*lintIgnore, string(p.Kind)) {
+				report = append(report, p)
+			}
+		}
+		report.Sort()
+		if len(report) > 0 {
+			newPrinter(os.Stdout).Print(report)
+		}
+		must.Must(report.Err())
+	},
+}
+
+var rulesTestCmd = &cobra.Command{
+	Use:   "test [FILE|DIR...]",
+	Short: "Run the tests in rule files, exit with an error if any fail.",
+	Long: `
+Run the tests in the 'tests' section of rule files, see templaterule.Test.
+Tests apply rules to start objects and compare the queries, they do not need a cluster.
+Rules are loaded from --rules or --config, tests are loaded from the arguments, or from the rule files if there are no arguments.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		e := newOfflineEngine()
+		if len(args) == 0 {
+			args = engineConfig().Rules
+		}
+		results := runRuleTests(e, args)
+		failed := 0
+		for _, r := range results {
+			if r.Error != "" {
+				failed++
+			}
+		}
+		newPrinter(os.Stdout).Print(results)
+		if failed > 0 {
+			must.Must(fmt.Errorf("%v of %v rule tests failed", failed, len(results)))
+		}
+	},
+}
+
+// ruleTestResult is the printed result of a templaterule.Test.
+type ruleTestResult struct {
+	File  string `json:"file"`
+	Test  string `json:"test"`
+	Error string `json:"error,omitempty"`
+}
+
+// runRuleTests runs the tests in rule files under paths.
+func runRuleTests(e *engine.Engine, paths []string) (results []ruleTestResult) {
+	for _, root := range paths {
+		must.Must(walkRules(root, func(path string, r io.Reader) error {
+			tests, err := templaterule.DecodeTestsFile(path, r)
+			if err != nil {
+				return err
+			}
+			for _, t := range tests {
+				result := ruleTestResult{File: path, Test: t.String()}
+				if err := t.Run(e); err != nil {
+					result.Error = err.Error()
+				}
+				results = append(results, result)
+			}
+			return nil
+		}))
+	}
+	return results
+}
+
+var lintFrom, lintIgnore *[]string
+
+func init() {
+	rootCmd.AddCommand(rulesCmd)
+	rulesCmd.AddCommand(rulesLintCmd)
+	rulesCmd.AddCommand(rulesTestCmd)
+	lintFrom = rulesLintCmd.Flags().StringSlice("from", []string{"alert"}, "All classes should be reachable from these domains, empty to skip the check")
+	lintIgnore = rulesLintCmd.Flags().StringSlice("ignore", nil, "Do not report problems of these kinds, for example 'unreachable'")
+}
+
+// lintRules loads rule files into e, returns problems from templaterule.Lint.
+// Files that cannot be loaded are reported as problems.
+func lintRules(e *engine.Engine, paths []string) (problems engine.Problems) {
+	for _, root := range paths {
+		err := walkRules(root, func(path string, r io.Reader) error {
+			ps, err := templaterule.LintFile(path, r, e)
+			for _, p := range ps {
+				p.Msg = fmt.Sprintf("%v: %v", path, p.Msg)
+				problems = append(problems, p)
+			}
+			for _, err := range multierr.Errors(err) {
+				problems = append(problems, engine.Problem{Kind: loadProblem, Msg: err.Error()})
+			}
+			return nil
+		})
+		if err != nil {
+			problems = append(problems, engine.Problem{Kind: loadProblem, Msg: fmt.Sprintf("%v: %v", root, err)})
+		}
+	}
+	return problems
+}
+
+// loadProblem is a rule file that cannot be loaded.
+const loadProblem engine.ProblemKind = "load"
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/korrel8r/korrel8r/internal/pkg/must"
+	"github.com/korrel8r/korrel8r/pkg/graph"
+	"github.com/spf13/cobra"
+)
+
+var showCmd = &cobra.Command{
+	Use:   "show FILE",
+	Short: "Show a correlation saved by 'correlate --save', without connecting to any stores.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		e := newOfflineEngine()
+		c := must.Must1(loadCorrelation(e, args[0]))
+		if *showGraph != "" {
+			must.Must(c.Graph.Encode(os.Stdout, *showGraph))
+			return
+		}
+		out := newCorrelateOutput(c, *showObjects)
+		if *showProvenance {
+			out.addProvenance(c)
+		}
+		newPrinter(os.Stdout).Print(out)
+	},
+}
+
+var (
+	showGraph                   *string
+	showObjects, showProvenance *bool
+)
+
+func init() {
+	rootCmd.AddCommand(showCmd)
+	showObjects = showCmd.Flags().Bool("objects", false, "Include result objects in the output")
+	showProvenance = showCmd.Flags().Bool("provenance", false, "Include each result object with the chains of rules and queries that found it")
+	showGraph = showCmd.Flags().String("graph", "", fmt.Sprintf("Print the correlation graph instead of a list of classes, format is one of %v", graph.Formats))
+}
@@ -16,6 +16,9 @@ var webCmd = &cobra.Command{
 		cfg := restConfig()
 		ui := must.Must1(webui.New(e, cfg, k8sClient(cfg)))
 		defer ui.Close()
+		ui.Timeout = *timeout
+		ui.Limits = limits()
+		ui.StatsFile = *ruleStats
 		log.Info("web ui listening", "addr", *httpAddr)
 		must.Must(http.ListenAndServe(*httpAddr, ui.Mux))
 	},
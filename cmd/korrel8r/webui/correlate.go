@@ -1,6 +1,7 @@
 package webui
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -14,10 +15,12 @@ import (
 	"strings"
 	"time"
 
+	"github.com/korrel8r/korrel8r/pkg/cache"
+	"github.com/korrel8r/korrel8r/pkg/domains"
+	"github.com/korrel8r/korrel8r/pkg/engine"
 	"github.com/korrel8r/korrel8r/pkg/graph"
 	"github.com/korrel8r/korrel8r/pkg/korrel8r"
 	"go.uber.org/multierr"
-	"gonum.org/v1/gonum/graph/encoding/dot"
 )
 
 // correlate web page handler.
@@ -28,22 +31,42 @@ type correlate struct {
 	Goal        string
 	Other       string
 	Neighbours  string
+	Upstream    string // Depth of reverse neighbourhood
+	Since       string // Constraint start time, RFC3339
+	Until       string // Constraint end time, RFC3339
+	Limit       string // Constraint limit
+	Stores      string // Store selection, comma separated domain=name
+	KShortest   string // Number of cheapest paths
+	MaxLength   string // Max path length
+	MaxPaths    string // Max number of paths
 
-	ShortPaths bool // All paths
-	RuleGraph  bool // Rules graph without results
-	// Goals to list as radio options, map[value]id
+	ShortPaths   bool // All paths
+	RuleGraph    bool // Rules graph without results
+	DiffPrevious bool // Compare with the previous correlation
+	AddStart     bool // Add the start query to the current correlation
+	// Goals to list as radio options, registered by domain plugins.
 	Goals []struct{ Value, Label string }
 
 	// Computed fields used by page template.
 	Time                            time.Time
 	StartQuery                      korrel8r.Query
 	StartClass, GoalClass           korrel8r.Class
+	Constraint                      *korrel8r.Constraint
+	StoreSelection                  map[string]string
 	Depth                           int
+	Reverse                         bool
+	PathLimits                      struct{ KShortest, MaxLength, MaxPaths int }
 	Graph                           *graph.Graph
+	Diff                            *engine.Diff // Changes from a previous correlation, may be nil.
 	Diagram, DiagramTxt, DiagramImg string
+	Exports                         map[string]string // Exported graph files by format.
 	ConsoleURL                      *url.URL
 	// Accumulated errors displayed on page
 	Err error
+	// Rules that did not apply, displayed on page.
+	RuleFailures engine.Failures
+	// Store cache statistics by store ID, displayed on page.
+	CacheStats map[string]cache.Stats
 
 	// Parent
 	ui *WebUI
@@ -53,19 +76,29 @@ type correlate struct {
 func (c *correlate) reset(params url.Values) {
 	ui := c.ui      // Save
 	*c = correlate{ // Overwrite
-		Start:       params.Get("start"),
-		StartDomain: params.Get("domain"),
-		Goal:        params.Get("goal"),
-		Other:       params.Get("other"),
-		Neighbours:  params.Get("neighbours"),
-		ShortPaths:  params.Get("short") == "true",
-		RuleGraph:   params.Get("rules") == "true",
-		Time:        time.Now(),
-	}
-	c.Goals = []struct{ Value, Label string }{
-		{"logs/infrastructure", "Logs"}, // FIXME wildcard
-		{"k8s/Event", "Events"},
-		{"metric/metric", "Metrics"},
+		Start:        params.Get("start"),
+		StartDomain:  params.Get("domain"),
+		Goal:         params.Get("goal"),
+		Other:        params.Get("other"),
+		Neighbours:   params.Get("neighbours"),
+		Upstream:     params.Get("upstream"),
+		Since:        params.Get("since"),
+		Until:        params.Get("until"),
+		Limit:        params.Get("limit"),
+		Stores:       params.Get("stores"),
+		KShortest:    params.Get("kshortest"),
+		MaxLength:    params.Get("maxlength"),
+		MaxPaths:     params.Get("maxpaths"),
+		ShortPaths:   params.Get("short") == "true",
+		RuleGraph:    params.Get("rules") == "true",
+		DiffPrevious: params.Get("diff") == "true",
+		AddStart:     params.Get("add") == "true",
+		Time:         time.Now(),
+	}
+	for _, p := range domains.List() {
+		for _, g := range p.Goals {
+			c.Goals = append(c.Goals, struct{ Value, Label string }{g.Class, g.Label})
+		}
 	}
 	c.ui = ui
 	c.ConsoleURL = c.ui.Console.BaseURL
@@ -79,6 +112,10 @@ func (c *correlate) reset(params url.Values) {
 func (c *correlate) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	defer req.Body.Close()
 	log.V(2).Info("serving correlate page", "uri", req.URL.RequestURI())
+	// Hold the lock until the page is rendered: the page shows the current correlation,
+	// which may be updated by another request, and Correlation.Add is not safe for concurrent use.
+	c.ui.mu.Lock()
+	defer c.ui.mu.Unlock()
 	c.update(req)
 	if c.Err != nil {
 		log.Error(c.Err, "page errors")
@@ -114,52 +151,137 @@ func (c *correlate) checkURL(u *url.URL, err error) *url.URL {
 
 func (c *correlate) update(req *http.Request) {
 	c.reset(req.URL.Query())
-	if !c.addErr(c.updateStart(), "start") {
-		// Prime the start node with initial results
-		start := c.Graph.NodeFor(c.StartClass)
-		if c.addErr(c.ui.Engine.Get(context.Background(), c.StartClass, c.StartQuery, start.Result)) {
-			return
-		}
-		start.QueryCounts.Put(c.StartQuery, len(start.Result.List()))
+	if req.Method == http.MethodPost {
+		c.load(req)
+		return
 	}
+	c.addErr(c.updateConstraint(), "constraint")
+	c.addErr(c.updateStores(), "stores")
+	c.addErr(c.updatePathLimits(), "paths")
+	c.addErr(c.updateStart(), "start")
 	c.addErr(c.updateGoal(), "goal")
 	if c.Err != nil {
 		return
 	}
-	follower := c.ui.Engine.Follower(context.Background())
-
-	if c.GoalClass != nil { // Paths from start to goal.
-		if c.ShortPaths {
-			c.Graph = c.Graph.ShortestPaths(c.StartClass, c.GoalClass)
-		} else {
-			c.Graph = c.Graph.AllPaths(c.StartClass, c.GoalClass)
-		}
+	r := engine.Request{
+		Start:         c.StartClass,
+		Goal:          c.GoalClass,
+		Depth:         c.Depth,
+		Reverse:       c.Reverse,
+		ShortestPaths: c.ShortPaths,
+		KShortest:     c.PathLimits.KShortest,
+		MaxPathLength: c.PathLimits.MaxLength,
+		MaxPaths:      c.PathLimits.MaxPaths,
+		RulesOnly:     c.RuleGraph,
+		Constraint:    c.Constraint,
+		Timeout:       c.ui.Timeout,
+		Limits:        c.ui.Limits,
+		Stores:        c.StoreSelection,
+	}
+	if c.StartQuery != nil {
+		r.Queries = []korrel8r.Query{c.StartQuery}
+	}
+	var result *engine.Correlation
+	var err error
+	if current := c.ui.current; c.AddStart && current != nil && current.Start == c.StartClass {
+		// Keep the options of the current correlation, only follow rules from the new start objects.
+		result, err = current, current.Add(context.Background(), r.Queries, nil)
+		c.GoalClass = current.Goal
 	} else {
-		// Find Neighbours
-		traverse := follower.Traverse
-		if c.RuleGraph {
-			traverse = nil
+		result, err = c.ui.Engine.Correlate(context.Background(), r)
+	}
+	if c.addErr(err) {
+		return
+	}
+	c.ui.current = result
+	if stats := c.ui.Engine.Stats(); stats != nil && c.ui.StatsFile != "" {
+		if err := stats.Save(c.ui.StatsFile); err != nil {
+			log.Error(err, "cannot save rule statistics")
 		}
-		c.Graph = c.Graph.Neighbours(c.StartClass, c.Depth, traverse)
-	}
-	if !c.RuleGraph {
-		c.addErr(c.Graph.Traverse(follower.Traverse))
-		c.Graph = c.Graph.Select(func(l *graph.Line) bool { // Remove lines with no queries
-			return l.QueryCounts.Total() > 0
-		})
-		if c.GoalClass != nil {
-			// Only include start->goal paths, remove dead-ends.
-			c.Graph = c.Graph.AllPaths(c.StartClass, c.GoalClass)
+	}
+	var before *engine.Correlation
+	if c.DiffPrevious {
+		before = c.loadFile(c.sessionFile())
+	}
+	c.show(result, before)
+}
+
+// loadFile loads a saved correlation from a file on the server.
+func (c *correlate) loadFile(name string) *engine.Correlation {
+	f, err := os.Open(name)
+	if c.addErr(err, "previous") {
+		return nil
+	}
+	defer f.Close()
+	result, err := c.ui.Engine.LoadCorrelation(f)
+	c.addErr(err, "previous")
+	return result
+}
+
+// load a correlation from a session file uploaded in a POST form.
+// If a "before" session is also uploaded, show the changes from before.
+func (c *correlate) load(req *http.Request) {
+	result := c.loadForm(req, "session")
+	if result == nil {
+		return
+	}
+	var before *engine.Correlation
+	if _, _, err := req.FormFile("before"); err == nil {
+		if before = c.loadForm(req, "before"); before == nil {
+			return
 		}
 	}
-	// Add start/goal nodes even if empty.
-	c.addClassNode(c.StartClass)
-	c.addClassNode(c.GoalClass)
+	c.ui.current = result
+	c.StartClass, c.GoalClass = result.Start, result.Goal
+	c.Start = korrel8r.ClassName(result.Start)
+	if result.Goal != nil {
+		c.Goal, c.Other = "other", korrel8r.ClassName(result.Goal)
+	}
+	c.show(result, before)
+}
+
+// loadForm loads a correlation from a file uploaded in a form field.
+func (c *correlate) loadForm(req *http.Request, field string) *engine.Correlation {
+	f, _, err := req.FormFile(field)
+	if c.addErr(err, field) {
+		return nil
+	}
+	defer f.Close()
+	result, err := c.ui.Engine.LoadCorrelation(f)
+	if c.addErr(err, field) {
+		return nil
+	}
+	return result
+}
 
+// show the results of a correlation on the page.
+// If before is not nil, show the changes from before to result.
+func (c *correlate) show(result, before *engine.Correlation) {
+	c.Graph = result.Graph
+	if before != nil {
+		c.Diff = engine.NewDiff(before, result)
+		c.Graph = c.Diff.Graph
+	}
+	for _, f := range result.Failures.Kind(engine.StoreFailed, engine.StoreTimeout) {
+		c.addErr(f)
+	}
+	c.RuleFailures = result.Failures.Kind(engine.RuleFailed)
+	c.CacheStats = c.ui.Engine.CacheStats()
+	log.V(1).Info("cache statistics", "cache", c.CacheStats)
 	c.updateDiagram()
+	c.saveSession(result)
 	log.V(2).Info("update complete")
 }
 
+// saveSession writes the correlation to a session file that can be downloaded and loaded later.
+func (c *correlate) saveSession(result *engine.Correlation) {
+	var b bytes.Buffer
+	file := c.sessionFile()
+	if !c.addErr(result.Save(&b), "session") && !c.addErr(os.WriteFile(file, b.Bytes(), 0664), "session") {
+		c.Exports["session"], _ = filepath.Rel(c.ui.dir, file)
+	}
+}
+
 func (c *correlate) updateStart() (err error) {
 	if c.Start == "" {
 		return errors.New("empty")
@@ -185,6 +307,70 @@ func (c *correlate) updateStart() (err error) {
 	return nil
 }
 
+func (c *correlate) updateConstraint() error {
+	if c.Since == "" && c.Until == "" && c.Limit == "" {
+		return nil
+	}
+	c.Constraint = &korrel8r.Constraint{}
+	for _, x := range []struct {
+		s string
+		t **time.Time
+	}{{c.Since, &c.Constraint.Start}, {c.Until, &c.Constraint.End}} {
+		if x.s != "" {
+			t, err := time.Parse(time.RFC3339, x.s)
+			if err != nil {
+				return err
+			}
+			*x.t = &t
+		}
+	}
+	if c.Limit != "" {
+		n, err := strconv.ParseUint(c.Limit, 10, 0)
+		if err != nil {
+			return err
+		}
+		if n > 0 { // 0 is no limit, as for the --limit flag.
+			limit := uint(n)
+			c.Constraint.Limit = &limit
+		}
+	}
+	if *c.Constraint == (korrel8r.Constraint{}) {
+		c.Constraint = nil
+	}
+	return nil
+}
+
+func (c *correlate) updateStores() error {
+	if c.Stores == "" {
+		return nil
+	}
+	c.StoreSelection = map[string]string{}
+	for _, s := range strings.Split(c.Stores, ",") {
+		domain, name, ok := strings.Cut(strings.TrimSpace(s), "=")
+		if !ok {
+			return fmt.Errorf("expected domain=name: %q", s)
+		}
+		c.StoreSelection[domain] = name
+	}
+	return nil
+}
+
+func (c *correlate) updatePathLimits() error {
+	for _, x := range []struct {
+		s string
+		n *int
+	}{{c.KShortest, &c.PathLimits.KShortest}, {c.MaxLength, &c.PathLimits.MaxLength}, {c.MaxPaths, &c.PathLimits.MaxPaths}} {
+		if x.s != "" {
+			n, err := strconv.Atoi(x.s)
+			if err != nil {
+				return err
+			}
+			*x.n = n
+		}
+	}
+	return nil
+}
+
 func (c *correlate) updateGoal() (err error) {
 	switch c.Goal {
 	case "neighbours":
@@ -193,6 +379,13 @@ func (c *correlate) updateGoal() (err error) {
 			c.Depth = 99
 		}
 		return nil
+	case "reverse":
+		c.Reverse = true
+		c.Depth, _ = strconv.Atoi(c.Upstream)
+		if c.Depth <= 0 {
+			c.Depth = 1
+		}
+		return nil
 	case "other":
 		c.GoalClass, err = c.ui.Engine.Class(c.Other)
 	default:
@@ -201,15 +394,6 @@ func (c *correlate) updateGoal() (err error) {
 	return err
 }
 
-func (c *correlate) addClassNode(class korrel8r.Class) {
-	if class != nil {
-		n := c.Graph.NodeFor(class)
-		if c.Graph.Node(n.ID()) == nil {
-			c.Graph.AddNode(n)
-		}
-	}
-}
-
 func (c *correlate) queryURLAttrs(a graph.Attrs, qcs graph.QueryCounts) {
 	if len(qcs) > 0 {
 		a["URL"] = c.checkURL(c.ui.Console.QueryToConsoleURL(qcs.Sort()[0].Query)).String()
@@ -222,8 +406,37 @@ const (
 	goalColor  = "pink"
 	fullColor  = "wheat"
 	emptyColor = "white"
+	// Border or line color for results that are incomplete due to store failures or timeouts.
+	incompleteColor = "orange"
 )
 
+// Colors for changes in a diff, see engine.Change.
+var diffColors = map[engine.Change]string{
+	engine.Added:   "palegreen",
+	engine.Removed: "lightpink",
+	engine.Changed: "lightskyblue",
+}
+
+// diffAttrs colors the nodes and lines of g that changed, and labels them with before and after counts.
+func (c *correlate) diffAttrs(g *graph.Graph) {
+	g.EachNode(func(n *graph.Node) {
+		if nd := c.Diff.Node(n.Class); nd != nil && nd.Change != engine.Unchanged {
+			a := n.Attrs
+			a["fillcolor"] = diffColors[nd.Change]
+			a["label"] = fmt.Sprintf("%v/%v\n(%v→%v)", n.Class.Domain(), korrel8r.ShortString(n.Class), nd.Before, nd.After)
+			a["tooltip"] += fmt.Sprintf("%v: +%v -%v\n", nd.Change, len(nd.Added), len(nd.Removed))
+		}
+	})
+	g.EachLine(func(l *graph.Line) {
+		if ld := c.Diff.Line(l.Rule); ld != nil && ld.Change != engine.Unchanged {
+			a := l.Attrs
+			a["color"] = diffColors[ld.Change]
+			a["penwidth"] = "3"
+			a["tooltip"] += fmt.Sprintf("%v: %v→%v\n", ld.Change, ld.Before, ld.After)
+		}
+	})
+}
+
 // updateDiagram generates an SVG diagram via graphviz.
 func (c *correlate) updateDiagram() {
 	g := c.Graph
@@ -240,6 +453,15 @@ func (c *correlate) updateDiagram() {
 		} else {
 			a["fillcolor"] = emptyColor
 		}
+		if n.Incomplete {
+			a["tooltip"] += "(incomplete)\n"
+			a["color"] = incompleteColor
+			a["style"] = strings.Join([]string{a["style"], "dashed"}, ",")
+		}
+		if n.Truncated {
+			a["tooltip"] += "(truncated, more data available)\n"
+			a["peripheries"] = "2"
+		}
 	})
 
 	g.EachLine(func(l *graph.Line) {
@@ -253,8 +475,19 @@ func (c *correlate) updateDiagram() {
 			a["style"] = "dashed"
 			a["color"] = "gray"
 		}
+		if l.Incomplete {
+			a["tooltip"] += "(incomplete)\n"
+			a["color"] = incompleteColor
+		}
+		if l.Truncated {
+			a["tooltip"] += "(truncated)\n"
+		}
 	})
 
+	if c.Diff != nil {
+		c.diffAttrs(g)
+	}
+
 	if c.StartClass != nil {
 		a := g.NodeFor(c.StartClass).Attrs
 		a["shape"] = "oval"
@@ -274,23 +507,43 @@ func (c *correlate) updateDiagram() {
 
 	// Write the graph files
 	baseName := filepath.Join(c.ui.dir, "files", "korrel8r")
-	if gv, err := dot.MarshalMulti(g, "", "", "  "); !c.addErr(err) {
-		gvFile := baseName + ".txt"
-		if !c.addErr(os.WriteFile(gvFile, gv, 0664)) {
-			// Render and write the graph image
-			svgFile := baseName + ".svg"
-			if !c.addErr(runDot("dot", "-v", "-K", layout, "-Tsvg", "-o", svgFile, gvFile)) {
-				c.Diagram, _ = filepath.Rel(c.ui.dir, svgFile)
-				c.DiagramTxt, _ = filepath.Rel(c.ui.dir, gvFile)
-			}
-			pngFile := baseName + ".png"
-			if !c.addErr(runDot("dot", "-v", "-K", layout, "-Tpng", "-o", pngFile, gvFile)) {
-				c.DiagramImg, _ = filepath.Rel(c.ui.dir, pngFile)
-			}
+	gvFile := baseName + ".txt"
+	if !c.addErr(writeGraph(g, gvFile, graph.FormatDOT)) {
+		// Render and write the graph image
+		svgFile := baseName + ".svg"
+		if !c.addErr(runDot("dot", "-v", "-K", layout, "-Tsvg", "-o", svgFile, gvFile)) {
+			c.Diagram, _ = filepath.Rel(c.ui.dir, svgFile)
+			c.DiagramTxt, _ = filepath.Rel(c.ui.dir, gvFile)
+		}
+		pngFile := baseName + ".png"
+		if !c.addErr(runDot("dot", "-v", "-K", layout, "-Tpng", "-o", pngFile, gvFile)) {
+			c.DiagramImg, _ = filepath.Rel(c.ui.dir, pngFile)
+		}
+	}
+	// Export formats that don't need graphviz.
+	c.Exports = map[string]string{}
+	for format, ext := range map[string]string{graph.FormatJSON: ".json", graph.FormatCytoscape: ".cy.json", graph.FormatMermaid: ".mmd"} {
+		file := baseName + ext
+		if !c.addErr(writeGraph(g, file, format)) {
+			c.Exports[format], _ = filepath.Rel(c.ui.dir, file)
 		}
 	}
 }
 
+// sessionFile is the file containing the most recent correlation.
+func (c *correlate) sessionFile() string {
+	return filepath.Join(c.ui.dir, "files", "korrel8r.session.json")
+}
+
+// writeGraph writes g to file in format.
+func writeGraph(g *graph.Graph, file, format string) error {
+	var b bytes.Buffer
+	if err := g.Encode(&b, format); err != nil {
+		return err
+	}
+	return os.WriteFile(file, b.Bytes(), 0664)
+}
+
 func runDot(cmdName string, args ...string) error {
 	cmd := exec.Command(cmdName, args[1:]...)
 	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
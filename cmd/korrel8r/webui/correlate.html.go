@@ -27,13 +27,39 @@ const correlateHTML = `
       <label for="neighbours"> <b>Neighbours</b>
         <input type="text" name="neighbours" id="neighboursText" value="{{.Neighbours}}" size="4">
       </label>
+      <br>
+      <input type="radio" name="goal" id="reverse" value="reverse" {{if eq .Goal "reverse"}}checked{{end}}>
+      <label for="reverse" title="Find classes with rules leading to the start, and follow inverse rules to find upstream objects."> <b>Upstream</b>
+        <input type="text" name="upstream" id="upstreamText" value="{{.Upstream}}" size="4">
+      </label>
     </p>
     <p>
       <b>Options:</b>
       <input type="checkbox" name="short" id="short" value="true" {{if .ShortPaths}}checked{{end}}/>
-      <label for="short" title="Follow shortest paths, instead of all paths.">Shortest paths</label>
+      <label for="short" title="Follow the cheapest paths, preferring rules that returned results in the past, instead of all paths.">Shortest paths</label>
+      <label for="kshortest" title="Follow only the K cheapest paths, if set.">K shortest</label>
+      <input type="text" name="kshortest" id="kshortest" value="{{.KShortest}}" size="4">
+      <label for="maxlength" title="Max number of rules on a path to the goal.">Max length</label>
+      <input type="text" name="maxlength" id="maxlength" value="{{.MaxLength}}" size="4">
+      <label for="maxpaths" title="Max number of paths to the goal.">Max paths</label>
+      <input type="text" name="maxpaths" id="maxpaths" value="{{.MaxPaths}}" size="4">
       <input type="checkbox" name="rules" id="rules" value="true" {{if .RuleGraph}}checked{{end}}/>
       <label for="rules" title="Graph rules without getting results.">Rules</label>
+      <input type="checkbox" name="diff" id="diff" value="true" {{if .DiffPrevious}}checked{{end}}/>
+      <label for="diff" title="Show changes from the previous correlation.">Diff with previous</label>
+      <input type="checkbox" name="add" id="add" value="true" {{if .AddStart}}checked{{end}}/>
+      <label for="add" title="Add the start objects to the current correlation, keeping its goal and options. Only rules from new objects are followed.">Add to current</label>
+      <label for="stores" title="Use only the named store for a domain, as domain=name,... Other domains use all their stores.">Stores</label>
+      <input type="text" name="stores" id="stores" value="{{.Stores}}">
+    </p>
+    <p>
+      <b>Constraint:</b>
+      <label for="since" title="Include only results after this time (RFC3339)">Since</label>
+      <input type="text" name="since" id="since" value="{{.Since}}">
+      <label for="until" title="Include only results before this time (RFC3339)">Until</label>
+      <input type="text" name="until" id="until" value="{{.Until}}">
+      <label for="limit" title="Max results per query">Limit</label>
+      <input type="text" name="limit" id="limit" value="{{.Limit}}" size="4">
     </p>
     <p>
       <input type="submit" id="submit" value="Update Graph">
@@ -41,6 +67,14 @@ const correlateHTML = `
     </p>
   </form>
 
+  <form method="post" enctype="multipart/form-data" action="/correlate">
+    <label for="session" title="Show a correlation saved from the session export link, or by 'korrel8r correlate --save'"><b>Load session: </b></label>
+    <input type="file" name="session" id="session" accept=".json">
+    <label for="before" title="Optional, show the changes from this session to the loaded session.">Compare with: </label>
+    <input type="file" name="before" id="before" accept=".json">
+    <input type="submit" value="Load">
+  </form>
+
   <script type="text/javascript">
    <!-- Show spinner while waiting -->
    function validate(form) {
@@ -56,6 +90,31 @@ const correlateHTML = `
     <div style="white-space: pre-line; border-width:2px; border-style:solid; border-color:red"> {{printf "%+v" .}}</div>
   {{end}}
 
+  {{with .RuleFailures}}
+    <hr>
+    <details>
+      <summary><b>Rules that did not apply ({{len .}})</b></summary>
+      <ul>
+        {{range .}}
+          <li><code>{{.Rule}}</code> [{{.Start}}]->[{{.Goal}}]: {{.Msg}}</li>
+        {{end}}
+      </ul>
+    </details>
+  {{end}}
+
+  {{with .Diff}}
+    <hr>
+    <h3>Changes</h3>
+    <ul>
+      {{range .Nodes}}{{if ne .Change "unchanged"}}
+        <li><code>{{.Class}}</code> {{.Change}}: {{.Before}} → {{.After}} objects (+{{len .Added}} -{{len .Removed}})</li>
+      {{end}}{{end}}
+      {{range .Lines}}{{if ne .Change "unchanged"}}
+        <li><code>{{.Rule}}</code> {{.Change}}: {{.Before}} → {{.After}} results</li>
+      {{end}}{{end}}
+    </ul>
+  {{end}}
+
   {{if .Diagram}}
     <hr>
     <h3>Diagram</h3>
@@ -65,19 +124,29 @@ const correlateHTML = `
       <a href="{{.DiagramTxt}}" target="_blank">Source</a>
     </p>
   {{end}}
+  {{with .Exports}}
+    <p align="center">
+      Export:
+      {{range $format, $file := .}}<a href="{{$file}}" target="_blank">{{$format}}</a> {{end}}
+    </p>
+  {{end}}
 
   <hr>
   <h3> Detailed Results </h3>
   <p>
     <ul>
       {{with .StartClass}}<li>Start: {{classname .}}</li>{{end}}
-      {{with .Depth}}<li>Depth: {{.}}</li>{{end}}
+      {{with .Depth}}<li>Depth: {{.}}{{if $.Reverse}} (upstream){{end}}</li>{{end}}
       {{with .GoalClass}}<li>Goal: {{classname .}}</li>{{end}}
+      {{range $store, $stats := .CacheStats}}
+        <li>Cache {{$store}}: {{$stats.Hits}} hits, {{$stats.Misses}} misses, {{$stats.Entries}} entries</li>
+      {{end}}
     </ul>
     <ul>
       {{range $node := (and .Graph .Graph.AllNodes)}}
         {{if $node.Result.List}}
-          <li><code><b>{{classname $node.Class}}</b> ({{len $node.Result.List}})</code>
+          <li><code><b>{{classname $node.Class}}</b> ({{len $node.Result.List}}{{if $node.Incomplete}}, incomplete{{end}}{{if $node.Truncated}}, truncated{{end}})</code>
+            <a href="/provenance?class={{classname $node.Class | urlquery}}" target="_blank" title="Show how each object was found">Why?</a>
             <ul>
               {{range ($.Graph.LinesTo .)}}
                 {{if .QueryCounts}}
@@ -86,8 +155,8 @@ const correlateHTML = `
                     {{range $qc := .QueryCounts.Sort}}
                       <li>
                         <a href="{{queryToConsole $qc.Query}}" target="_blank">Console</a> /
-                        <a href="/stores/{{$node.Class}}?query={{json $qc.Query | urlquery}}" target="_blank">Data</a>
-                        ({{$qc.Count}})
+                        <a href="/stores/{{$node.Class}}?query={{json $qc.Query | urlquery}}{{with $.Constraint}}&constraint={{json . | urlquery}}{{end}}" target="_blank">Data</a>
+                        ({{$qc.Count}}{{if gt (len $qc.Stores) 1}}:{{range $store, $count := $qc.Stores}} {{$store}}={{$count}}{{end}}{{end}})
                         <pre>{{$qc.Query | json}}</pre>
                       </li>
                     {{end}}
@@ -0,0 +1,60 @@
+package webui
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/korrel8r/korrel8r/pkg/graph"
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+)
+
+// provenanceHandler shows how objects of a class in the current correlation were found.
+type provenanceHandler struct{ ui *WebUI }
+
+func (h *provenanceHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h.ui.mu.Lock() // Don't read the current graph while a correlate request updates it.
+	defer h.ui.mu.Unlock()
+	c := h.ui.current
+	if c == nil {
+		httpError(w, errors.New("no current correlation"), http.StatusNotFound)
+		return
+	}
+	class, err := h.ui.Engine.Class(req.URL.Query().Get("class"))
+	if httpError(w, err, http.StatusNotFound) {
+		return
+	}
+	n := c.Graph.NodeFor(class)
+	type objectChains struct {
+		Object korrel8r.Object
+		Chains [][]graph.Step
+	}
+	var objects []objectChains
+	for _, o := range n.Result.List() {
+		objects = append(objects, objectChains{Object: o, Chains: n.Provenance(o)})
+	}
+	serveTemplate(w, h.ui.Page("provenance"), provenanceHTML, map[string]any{"class": class, "objects": objects})
+}
+
+const provenanceHTML = `
+{{define "body"}}
+    <h3>Provenance of {{classname .class}} ({{len .objects}})</h3>
+    {{range .objects}}
+        <hr>
+        <details>
+            <summary><code>{{json .Object | printf "%.120s"}}</code></summary>
+            <pre>{{yaml .Object}}</pre>
+        </details>
+        <ol>
+            {{range .Chains}}
+                <li>
+                    {{range $i, $step := .}}
+                        {{if $step.Rule}}&rarr; <code>{{$step.Rule}}</code> &rarr;{{end}}
+                        <span title="{{with $step.Query}}{{json .}}{{end}}"><b>{{classname $step.Class}}</b></span>
+                        {{if eq $i 0}}<code>{{json $step.Object | printf "%.80s"}}</code>{{end}}
+                    {{end}}
+                </li>
+            {{end}}
+        </ol>
+    {{end}}
+{{end}}
+`
@@ -2,6 +2,7 @@ package webui
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"path"
 
@@ -26,8 +27,15 @@ func (h *storeHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	if httpError(w, err, http.StatusNotFound) {
 		return
 	}
+	var constraint *korrel8r.Constraint
+	if s := params.Get("constraint"); s != "" {
+		constraint = &korrel8r.Constraint{}
+		if httpError(w, json.Unmarshal([]byte(s), constraint), http.StatusBadRequest) {
+			return
+		}
+	}
 	result := korrel8r.NewResult(query.Class())
-	err = store.Get(context.Background(), query, result)
+	err = store.Get(context.Background(), query, constraint, result)
 	data := map[string]any{
 		"query":  query,
 		"err":    err,
@@ -9,6 +9,8 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"context"
 
@@ -30,7 +32,13 @@ type WebUI struct {
 	Engine  *engine.Engine
 	Console *console.Console
 	Mux     *http.ServeMux
-	dir     string
+	Timeout time.Duration // Timeout for each correlation, 0 means no timeout.
+	Limits  engine.Limits // Limits for each correlation.
+	// StatsFile is updated with Engine.Stats after each correlation, if not empty.
+	StatsFile string
+	dir       string
+	current   *engine.Correlation // Most recent correlation, see engine.Correlation.Add
+	mu        sync.Mutex          // Guards current, its graph is read and updated by page handlers.
 }
 
 func New(e *engine.Engine, cfg *rest.Config, c client.Client) (*WebUI, error) {
@@ -54,6 +62,7 @@ func New(e *engine.Engine, cfg *rest.Config, c client.Client) (*WebUI, error) {
 	ui.Mux.Handle("/files/", http.FileServer(http.Dir(ui.dir)))
 	ui.Mux.Handle("/static/", http.FileServer(http.FS(static)))
 	ui.Mux.Handle("/stores/", &storeHandler{ui: ui})
+	ui.Mux.Handle("/provenance", &provenanceHandler{ui: ui})
 	ui.Mux.HandleFunc("/error/", func(w http.ResponseWriter, req *http.Request) {
 		// So links that can't be generated can link to the error message.
 		httpError(w, errors.New(req.URL.Query().Get("err")), http.StatusInternalServerError)
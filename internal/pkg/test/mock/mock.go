@@ -5,11 +5,13 @@ package mock
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/korrel8r/korrel8r/pkg/korrel8r"
 )
@@ -58,7 +60,12 @@ func (d Domain) Classes() (classes []korrel8r.Class) {
 	return classes
 }
 
-func (Domain) UnmarshalQuery(b []byte) (korrel8r.Query, error) { panic(NoMockErr) }
+// UnmarshalQuery decodes a query from a JSON string.
+func (Domain) UnmarshalQuery(b []byte) (korrel8r.Query, error) {
+	var q Query
+	err := json.Unmarshal(b, &q)
+	return q, err
+}
 
 // Query implemented as a string
 type Query string
@@ -136,14 +143,24 @@ func Rules(startGoal ...string) []korrel8r.Rule {
 }
 
 // Store is a map of mock query strings to sets of objects.
+//
+// Store methods are safe for concurrent use.
 type Store map[string][]korrel8r.Object
 
+var storeMu sync.RWMutex // Guards all Store maps.
+
 func (s Store) Domain() korrel8r.Domain { panic(NoMockErr) }
 
-// Get returns the objects associated with the query
-func (s Store) Get(_ context.Context, q korrel8r.Query, r korrel8r.Appender) error {
+// Get returns the objects associated with the query, up to the constraint limit.
+func (s Store) Get(_ context.Context, q korrel8r.Query, c *korrel8r.Constraint, r korrel8r.Appender) error {
 	mq := q.(Query)
-	for _, o := range s[mq.String()] {
+	storeMu.RLock()
+	objects := s[mq.String()]
+	storeMu.RUnlock()
+	for i, o := range objects {
+		if c.LimitReached(i) {
+			break
+		}
 		r.Append(o)
 	}
 	return nil
@@ -154,6 +171,8 @@ func (s Store) Resolve(korrel8r.Query) *url.URL { panic("not implemented") }
 // NewQuery returns a query that will return the given objects.
 func (s Store) NewQuery(objs ...string) korrel8r.Query {
 	r := Query(strings.Join(objs, "&"))
+	storeMu.Lock()
+	defer storeMu.Unlock()
 	s[r.String()] = Objects(objs...)
 	return r
 }
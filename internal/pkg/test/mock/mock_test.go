@@ -41,7 +41,7 @@ func TestObject(t *testing.T) {
 func TestStore_Get(t *testing.T) {
 	r := korrel8r.NewListResult()
 	s := Store{"test": Objects("X/foo:x", "Y/bar.y", "foo:a", "bar:b", ":u", ":v")}
-	require.NoError(t, s.Get(context.Background(), Query("test"), r))
+	require.NoError(t, s.Get(context.Background(), Query("test"), nil, r))
 	want := Objects("X/foo:x", "Y/bar.y", "foo:a", "bar:b", ":u", ":v")
 	assert.Equal(t, want, r.List())
 }
@@ -50,7 +50,16 @@ func TestStore_NewQuery(t *testing.T) {
 	r := korrel8r.NewListResult()
 	s := Store{}
 	q := s.NewQuery("X/foo:x", "Y/bar.y", "foo:a", "bar:b", ":u", ":v")
-	require.NoError(t, s.Get(context.Background(), q, r))
+	require.NoError(t, s.Get(context.Background(), q, nil, r))
 	want := Objects("X/foo:x", "Y/bar.y", "foo:a", "bar:b", ":u", ":v")
 	assert.Equal(t, want, r.List())
 }
+
+func TestStore_GetLimit(t *testing.T) {
+	r := korrel8r.NewListResult()
+	s := Store{}
+	q := s.NewQuery("foo:a", "foo:b", "foo:c")
+	limit := uint(2)
+	require.NoError(t, s.Get(context.Background(), q, &korrel8r.Constraint{Limit: &limit}, r))
+	assert.Equal(t, Objects("foo:a", "foo:b"), r.List())
+}
@@ -0,0 +1,110 @@
+// package cache provides a korrel8r.Store wrapper that caches query results.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+)
+
+var _ korrel8r.Store = &Store{}
+
+// Store caches the results of Get calls to another store.
+//
+// Results are keyed by the JSON form of the query and constraint, like graph.QueryCounts.
+// Entries expire after TTL, and the least recently used entries are evicted to keep at most Size entries.
+// Errors are not cached. Store is safe for concurrent use.
+type Store struct {
+	korrel8r.Store
+	TTL  time.Duration // Time to live for cached results, 0 means no expiry.
+	Size int           // Max number of cached results, 0 means no limit.
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // Values are *entry
+	lru     *list.List               // Most recently used at the front.
+	stats   Stats
+	now     func() time.Time
+}
+
+// Stats are counters for cache hits and misses.
+type Stats struct {
+	Hits    int `json:"hits"`
+	Misses  int `json:"misses"`
+	Entries int `json:"entries"`
+}
+
+type entry struct {
+	key     string
+	objects []korrel8r.Object
+	expires time.Time
+}
+
+// New returns a caching wrapper for s.
+func New(s korrel8r.Store, ttl time.Duration, size int) *Store {
+	return &Store{Store: s, TTL: ttl, Size: size, entries: map[string]*list.Element{}, lru: list.New(), now: time.Now}
+}
+
+// Get returns cached results if available, otherwise calls Get on the wrapped store.
+func (s *Store) Get(ctx context.Context, q korrel8r.Query, c *korrel8r.Constraint, r korrel8r.Appender) error {
+	key := korrel8r.JSONString(q)
+	if c != nil {
+		key = key + korrel8r.JSONString(c)
+	}
+	if objects, ok := s.lookup(key); ok {
+		r.Append(objects...)
+		return nil
+	}
+	result := korrel8r.NewListResult()
+	if err := s.Store.Get(ctx, q, c, result); err != nil {
+		r.Append(result.List()...) // Pass on partial results, but don't cache them.
+		return err
+	}
+	s.add(key, result.List())
+	r.Append(result.List()...)
+	return nil
+}
+
+// Stats returns the current cache statistics.
+func (s *Store) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := s.stats
+	stats.Entries = s.lru.Len()
+	return stats
+}
+
+func (s *Store) lookup(key string) ([]korrel8r.Object, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el := s.entries[key]; el != nil {
+		e := el.Value.(*entry)
+		if s.TTL == 0 || s.now().Before(e.expires) {
+			s.lru.MoveToFront(el)
+			s.stats.Hits++
+			return e.objects, true
+		}
+		s.remove(el) // Expired
+	}
+	s.stats.Misses++
+	return nil, false
+}
+
+func (s *Store) add(key string, objects []korrel8r.Object) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el := s.entries[key]; el != nil { // Added concurrently.
+		s.remove(el)
+	}
+	s.entries[key] = s.lru.PushFront(&entry{key: key, objects: objects, expires: s.now().Add(s.TTL)})
+	for s.Size > 0 && s.lru.Len() > s.Size {
+		s.remove(s.lru.Back())
+	}
+}
+
+func (s *Store) remove(el *list.Element) {
+	delete(s.entries, el.Value.(*entry).key)
+	s.lru.Remove(el)
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/korrel8r/korrel8r/internal/pkg/test/mock"
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countStore counts calls to Get, and fails queries in fail.
+type countStore struct {
+	mock.Store
+	calls int
+	fail  korrel8r.Query
+}
+
+func (s *countStore) Get(ctx context.Context, q korrel8r.Query, c *korrel8r.Constraint, r korrel8r.Appender) error {
+	s.calls++
+	if q == s.fail {
+		return errors.New("failed")
+	}
+	return s.Store.Get(ctx, q, c, r)
+}
+
+func get(t *testing.T, s korrel8r.Store, q korrel8r.Query, c *korrel8r.Constraint) []korrel8r.Object {
+	t.Helper()
+	r := korrel8r.NewListResult()
+	require.NoError(t, s.Get(context.Background(), q, c, r))
+	return r.List()
+}
+
+func TestStore_Get(t *testing.T) {
+	inner := &countStore{Store: mock.Store{}}
+	q := inner.NewQuery("a:1", "a:2")
+	s := New(inner, 0, 0)
+	assert.Equal(t, mock.Objects("a:1", "a:2"), get(t, s, q, nil))
+	assert.Equal(t, mock.Objects("a:1", "a:2"), get(t, s, q, nil))
+	assert.Equal(t, 1, inner.calls)
+	// Different constraint is a different key.
+	limit := uint(1)
+	assert.Equal(t, mock.Objects("a:1"), get(t, s, q, &korrel8r.Constraint{Limit: &limit}))
+	assert.Equal(t, 2, inner.calls)
+	assert.Equal(t, Stats{Hits: 1, Misses: 2, Entries: 2}, s.Stats())
+}
+
+func TestStore_TTL(t *testing.T) {
+	inner := &countStore{Store: mock.Store{}}
+	q := inner.NewQuery("a:1")
+	s := New(inner, time.Minute, 0)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	get(t, s, q, nil)
+	now = now.Add(30 * time.Second)
+	get(t, s, q, nil)
+	assert.Equal(t, 1, inner.calls)
+	now = now.Add(time.Minute)
+	get(t, s, q, nil)
+	assert.Equal(t, 2, inner.calls)
+}
+
+func TestStore_Size(t *testing.T) {
+	inner := &countStore{Store: mock.Store{}}
+	q1, q2, q3 := inner.NewQuery("a:1"), inner.NewQuery("a:2"), inner.NewQuery("a:3")
+	s := New(inner, 0, 2)
+	get(t, s, q1, nil)
+	get(t, s, q2, nil)
+	get(t, s, q1, nil) // q1 most recently used
+	get(t, s, q3, nil) // evicts q2
+	assert.Equal(t, 3, inner.calls)
+	get(t, s, q1, nil)
+	assert.Equal(t, 3, inner.calls)
+	get(t, s, q2, nil)
+	assert.Equal(t, 4, inner.calls)
+	assert.Equal(t, 2, s.Stats().Entries)
+}
+
+func TestStore_Error(t *testing.T) {
+	inner := &countStore{Store: mock.Store{}}
+	inner.fail = inner.NewQuery("a:1")
+	s := New(inner, 0, 0)
+	for i := 0; i < 2; i++ {
+		assert.Error(t, s.Get(context.Background(), inner.fail, nil, korrel8r.NewListResult()))
+	}
+	assert.Equal(t, 2, inner.calls)
+	assert.Equal(t, Stats{Misses: 2}, s.Stats())
+}
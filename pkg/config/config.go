@@ -0,0 +1,146 @@
+// package config loads korrel8r configuration files.
+//
+// A configuration file is YAML or JSON, for example:
+//
+//	rules:
+//	  - rules # Relative paths are relative to the directory containing the configuration file.
+//	stores:
+//	  - domain: k8s
+//	  - domain: logs
+//	    name: infra # A domain can have several stores with different names.
+//	    kind: lokistack
+//	    url: https://lokistack.example.com
+//	    certificateAuthority: /etc/korrel8r/ca.crt
+//	    bearerTokenFile: /var/run/secrets/kubernetes.io/serviceaccount/token
+//	    timeout: 30s
+//	  - domain: alert
+//	    kind: openshift
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/yaml"
+)
+
+// Config for a korrel8r engine.
+type Config struct {
+	// Rules is a list of files or directories containing rules.
+	Rules []string `json:"rules,omitempty"`
+	// Stores is a list of store configurations.
+	Stores []Store `json:"stores,omitempty"`
+}
+
+// Store kinds that are common to more than one domain.
+const (
+	// KindOpenshift discovers the store on the current OpenShift cluster, this is the default if URL is empty.
+	KindOpenshift = "openshift"
+)
+
+// Store configures a store for a domain.
+type Store struct {
+	// Domain name of the store, required.
+	Domain string `json:"domain"`
+	// Name of the store, optional. Stores for the same domain must have different names.
+	Name string `json:"name,omitempty"`
+	// Kind of store, the meaning depends on the domain.
+	// If empty, defaults to KindOpenshift if URL is empty, or the domain's default kind if not.
+	Kind string `json:"kind,omitempty"`
+	// URL of the store service.
+	URL string `json:"url,omitempty"`
+	// Params are additional domain-specific parameters, e.g. a second URL for stores that use two services.
+	Params map[string]string `json:"params,omitempty"`
+
+	// CertificateAuthority is a path to a PEM file of trusted certificate authorities.
+	CertificateAuthority string `json:"certificateAuthority,omitempty"`
+	// BearerTokenFile is a path to a file containing a bearer token for authentication.
+	BearerTokenFile string `json:"bearerTokenFile,omitempty"`
+
+	// Concurrency limits concurrent requests to the store, 0 means no limit.
+	Concurrency int `json:"concurrency,omitempty"`
+	// Timeout for each request to the store, 0 means no timeout.
+	Timeout Duration `json:"timeout,omitempty"`
+	// Cache results from the store, if not nil.
+	Cache *Cache `json:"cache,omitempty"`
+}
+
+// Cache configures a store cache, see cache.New.
+type Cache struct {
+	TTL  Duration `json:"ttl,omitempty"`
+	Size int      `json:"size,omitempty"`
+}
+
+// Duration is a time.Duration that is marshaled as a string, e.g. "1m30s".
+type Duration struct{ time.Duration }
+
+func (d Duration) MarshalJSON() ([]byte, error) { return json.Marshal(d.String()) }
+
+func (d *Duration) UnmarshalJSON(b []byte) (err error) {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	d.Duration, err = time.ParseDuration(s)
+	return err
+}
+
+// Load a configuration file.
+// Relative rule paths are made relative to the directory of the file.
+func Load(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	for i, r := range c.Rules {
+		if !filepath.IsAbs(r) {
+			c.Rules[i] = filepath.Join(filepath.Dir(path), r)
+		}
+	}
+	names := map[[2]string]bool{}
+	for _, s := range c.Stores {
+		if s.Domain == "" {
+			return nil, fmt.Errorf("%v: store has no domain", path)
+		}
+		key := [2]string{s.Domain, s.Name}
+		if names[key] {
+			return nil, fmt.Errorf("%v: duplicate store for domain %v: %q", path, s.Domain, s.Name)
+		}
+		names[key] = true
+	}
+	return c, nil
+}
+
+// KindOrDefault returns the store Kind, or a default if Kind is empty.
+// The default is KindOpenshift if URL is empty, urlKind otherwise.
+func (s *Store) KindOrDefault(urlKind string) string {
+	switch {
+	case s.Kind != "":
+		return s.Kind
+	case s.URL == "":
+		return KindOpenshift
+	default:
+		return urlKind
+	}
+}
+
+// HTTPClient returns a HTTP client using the store's CertificateAuthority and BearerTokenFile.
+// If neither is set, it returns nil, meaning use a default client.
+func (s *Store) HTTPClient() (*http.Client, error) {
+	if s.CertificateAuthority == "" && s.BearerTokenFile == "" {
+		return nil, nil
+	}
+	return rest.HTTPClientFor(&rest.Config{
+		TLSClientConfig: rest.TLSClientConfig{CAFile: s.CertificateAuthority},
+		BearerTokenFile: s.BearerTokenFile,
+	})
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeFile(t, "korrel8r.yaml", `
+rules: [ "rules", "/abs/rules" ]
+stores:
+  - domain: k8s
+  - domain: logs
+    name: infra
+    kind: loki
+    url: http://loki
+    timeout: 30s
+    concurrency: 4
+    cache: { ttl: 1m, size: 10 }
+  - domain: alert
+    url: http://alertmanager
+    params: { prometheusURL: "http://prometheus" }
+`)
+	c, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, &Config{
+		Rules: []string{filepath.Join(filepath.Dir(path), "rules"), "/abs/rules"},
+		Stores: []Store{
+			{Domain: "k8s"},
+			{Domain: "logs", Name: "infra", Kind: "loki", URL: "http://loki", Timeout: Duration{30 * time.Second}, Concurrency: 4,
+				Cache: &Cache{TTL: Duration{time.Minute}, Size: 10}},
+			{Domain: "alert", URL: "http://alertmanager", Params: map[string]string{"prometheusURL": "http://prometheus"}},
+		},
+	}, c)
+	assert.Equal(t, KindOpenshift, c.Stores[0].KindOrDefault("x"))
+	assert.Equal(t, "loki", c.Stores[1].KindOrDefault("x"))
+	assert.Equal(t, "x", c.Stores[2].KindOrDefault("x"))
+}
+
+func TestLoad_Errors(t *testing.T) {
+	for _, x := range []struct{ content, err string }{
+		{"stores: [ { kind: loki } ]", "store has no domain"},
+		{"stores: [ { domain: logs, nonesuch: x } ]", `unknown field "nonesuch"`},
+		{"stores: [ { domain: logs, timeout: xx } ]", `invalid duration "xx"`},
+		{"stores: [ { domain: logs, name: a }, { domain: logs, name: a } ]", `duplicate store for domain logs: "a"`},
+	} {
+		t.Run(x.content, func(t *testing.T) {
+			_, err := Load(writeFile(t, "bad.yaml", x.content))
+			assert.ErrorContains(t, err, x.err)
+		})
+	}
+}
+
+func TestStore_HTTPClient(t *testing.T) {
+	hc, err := (&Store{}).HTTPClient()
+	require.NoError(t, err)
+	assert.Nil(t, hc)
+
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+	s := &Store{BearerTokenFile: writeFile(t, "token", "secret")}
+	hc, err = s.HTTPClient()
+	require.NoError(t, err)
+	resp, err := hc.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "Bearer secret", auth)
+}
@@ -182,7 +182,23 @@ func (q *Query) matches(a v1.Alert) bool {
 	return true
 }
 
-func (s Store) Get(ctx context.Context, query korrel8r.Query, result korrel8r.Appender) error {
+// active returns true if the alert was active at some time permitted by the constraint.
+func (o *Object) active(c *korrel8r.Constraint) bool {
+	start := o.ActiveAt
+	if start.IsZero() {
+		start = o.StartsAt
+	}
+	if !start.IsZero() && c.CompareTime(start) > 0 {
+		return false // Started after the constraint interval.
+	}
+	if !o.EndsAt.IsZero() && c.CompareTime(o.EndsAt) < 0 {
+		return false // Ended before the constraint interval.
+	}
+	return true
+}
+
+// Get alerts matching the query that were active during the constraint interval.
+func (s Store) Get(ctx context.Context, query korrel8r.Query, constraint *korrel8r.Constraint, result korrel8r.Appender) error {
 	q, err := impl.TypeAssert[*Query](query)
 	if err != nil {
 		return err
@@ -257,8 +273,15 @@ func (s Store) Get(ctx context.Context, query korrel8r.Query, result korrel8r.Ap
 		}
 	}
 
+	n := 0
 	for _, a := range alerts {
-		result.Append(a)
+		if constraint.LimitReached(n) {
+			break
+		}
+		if a.active(constraint) {
+			result.Append(a)
+			n++
+		}
 	}
 
 	return nil
@@ -0,0 +1,47 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/korrel8r/korrel8r/pkg/config"
+	"github.com/korrel8r/korrel8r/pkg/domains"
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObject_Active(t *testing.T) {
+	t0 := time.Now()
+	t1, t2, t3 := t0.Add(time.Minute), t0.Add(2*time.Minute), t0.Add(3*time.Minute)
+	for _, x := range []struct {
+		name string
+		o    Object
+		c    *korrel8r.Constraint
+		want bool
+	}{
+		{"no constraint", Object{ActiveAt: t1}, nil, true},
+		{"active before end", Object{ActiveAt: t1}, &korrel8r.Constraint{End: &t2}, true},
+		{"active after end", Object{ActiveAt: t3}, &korrel8r.Constraint{End: &t2}, false},
+		{"starts after end", Object{StartsAt: t3}, &korrel8r.Constraint{End: &t2}, false},
+		{"ended before start", Object{StartsAt: t0, EndsAt: t1}, &korrel8r.Constraint{Start: &t2}, false},
+		{"overlaps start", Object{StartsAt: t0, EndsAt: t2}, &korrel8r.Constraint{Start: &t1, End: &t3}, true},
+		{"still firing", Object{ActiveAt: t0}, &korrel8r.Constraint{Start: &t2}, true},
+	} {
+		t.Run(x.name, func(t *testing.T) { assert.Equal(t, x.want, x.o.active(x.c)) })
+	}
+}
+
+func TestNewStore_Alertmanager(t *testing.T) {
+	for _, sc := range []config.Store{
+		{Kind: KindAlertmanager, URL: "http://alertmanager"},
+		{Kind: KindAlertmanager, Params: map[string]string{PrometheusURLParam: "http://prometheus"}},
+	} {
+		_, err := newStore(domains.StoreEnv{}, sc)
+		assert.EqualError(t, err, "alertmanager store needs both a URL and a prometheusURL parameter")
+	}
+	s, err := newStore(domains.StoreEnv{}, config.Store{
+		Kind: KindAlertmanager, URL: "http://alertmanager", Params: map[string]string{PrometheusURLParam: "http://prometheus"},
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, s)
+}
@@ -0,0 +1,50 @@
+package alert
+
+import (
+	"fmt"
+
+	"github.com/korrel8r/korrel8r/pkg/config"
+	"github.com/korrel8r/korrel8r/pkg/domains"
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+)
+
+// Store kinds, see config.Store.Kind
+const (
+	// KindAlertmanager uses the Alertmanager at URL, and the Prometheus at Params[PrometheusURLParam].
+	KindAlertmanager   = "alertmanager"
+	PrometheusURLParam = "prometheusURL"
+)
+
+func init() {
+	domains.Register(domains.Plugin{
+		Domain:       Domain,
+		NewStore:     newStore,
+		ConsolePaths: []string{"/monitoring/alerts"},
+	})
+}
+
+func newStore(env domains.StoreEnv, sc config.Store) (korrel8r.Store, error) {
+	switch kind := sc.KindOrDefault(KindAlertmanager); kind {
+	case config.KindOpenshift:
+		return NewOpenshiftStore(env.Context, env.RESTConfig)
+	case KindAlertmanager:
+		if sc.URL == "" || sc.Params[PrometheusURLParam] == "" {
+			return nil, fmt.Errorf("%v store needs both a URL and a %v parameter", KindAlertmanager, PrometheusURLParam)
+		}
+		hc, err := env.HTTPClient(sc)
+		if err != nil {
+			return nil, err
+		}
+		alertmanagerURL, err := domains.ParseURL(sc.URL)
+		if err != nil {
+			return nil, err
+		}
+		prometheusURL, err := domains.ParseURL(sc.Params[PrometheusURLParam])
+		if err != nil {
+			return nil, err
+		}
+		return NewStore(alertmanagerURL, prometheusURL, hc)
+	default:
+		return nil, fmt.Errorf("unknown store kind for domain %v: %v", Domain, kind)
+	}
+}
@@ -0,0 +1,125 @@
+// package domains is a registry of korrel8r domains.
+//
+// A domain package registers a Plugin in an init() function.
+// Programs choose the domains they include by importing domain packages, for example:
+//
+//	import _ "github.com/korrel8r/korrel8r/pkg/domains/k8s"
+//
+// A domain can be implemented in a separate Go module and linked into a custom build the same way.
+package domains
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/korrel8r/korrel8r/pkg/config"
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+	"golang.org/x/exp/slices"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Plugin describes a domain and how to create its stores.
+type Plugin struct {
+	// Domain implementation, required.
+	Domain korrel8r.Domain
+	// NewStore creates a store from its configuration, nil if the domain has no stores.
+	NewStore func(StoreEnv, config.Store) (korrel8r.Store, error)
+	// ConsolePaths are OpenShift console URL path prefixes for pages that show data for this domain.
+	// The domain or its stores must implement console.Converter to convert the URLs.
+	ConsolePaths []string
+	// Goals are suggested goal classes for user interfaces.
+	Goals []Goal
+}
+
+// Goal is a suggested goal class.
+type Goal struct {
+	Label string // Short label for the goal, e.g. "Logs".
+	Class string // Full class name, 'domain/class'
+}
+
+// StoreEnv is the environment for creating stores.
+type StoreEnv struct {
+	Context context.Context
+	// RESTConfig for the current cluster, used to discover stores and to authenticate.
+	RESTConfig *rest.Config
+}
+
+// Client returns a new client for the cluster.
+func (env StoreEnv) Client() (client.Client, error) {
+	return client.New(env.RESTConfig, client.Options{})
+}
+
+// HTTPClient returns a client for a store's configured credentials, or http.DefaultClient if there are none.
+func (env StoreEnv) HTTPClient(sc config.Store) (*http.Client, error) {
+	hc, err := sc.HTTPClient()
+	if hc == nil && err == nil {
+		hc = http.DefaultClient
+	}
+	return hc, err
+}
+
+// ParseURL parses a HTTP or HTTPS URL.
+func ParseURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("%q: unsupported scheme", s)
+	}
+	return u, nil
+}
+
+var (
+	mu      sync.Mutex
+	plugins = map[string]*Plugin{}
+)
+
+// Register a domain plugin. Panics if p.Domain is nil or is already registered.
+func Register(p Plugin) {
+	mu.Lock()
+	defer mu.Unlock()
+	if p.Domain == nil {
+		panic("domains: Register domain is nil")
+	}
+	name := p.Domain.String()
+	if _, ok := plugins[name]; ok {
+		panic("domains: Register called twice for domain " + name)
+	}
+	plugins[name] = &p
+}
+
+// Get returns the plugin for a domain name, or nil if not registered.
+func Get(name string) *Plugin {
+	mu.Lock()
+	defer mu.Unlock()
+	return plugins[name]
+}
+
+// List returns all registered plugins, sorted by domain name.
+func List() []*Plugin {
+	mu.Lock()
+	defer mu.Unlock()
+	list := make([]*Plugin, 0, len(plugins))
+	for _, p := range plugins {
+		list = append(list, p)
+	}
+	slices.SortFunc(list, func(a, b *Plugin) bool { return a.Domain.String() < b.Domain.String() })
+	return list
+}
+
+// NewStore creates a store for a configuration using the registered plugin for its domain.
+func NewStore(env StoreEnv, sc config.Store) (korrel8r.Store, error) {
+	p := Get(sc.Domain)
+	if p == nil {
+		return nil, fmt.Errorf("domain not found: %v", sc.Domain)
+	}
+	if p.NewStore == nil {
+		return nil, fmt.Errorf("no stores for domain: %v", sc.Domain)
+	}
+	return p.NewStore(env, sc)
+}
@@ -0,0 +1,49 @@
+package domains
+
+import (
+	"testing"
+
+	"github.com/korrel8r/korrel8r/internal/pkg/test/mock"
+	"github.com/korrel8r/korrel8r/pkg/config"
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegister(t *testing.T) {
+	store := mock.Store{}
+	Register(Plugin{
+		Domain: mock.Domain("b"),
+		NewStore: func(_ StoreEnv, sc config.Store) (korrel8r.Store, error) {
+			return store, nil
+		},
+		Goals: []Goal{{Label: "B", Class: "b/x"}},
+	})
+	Register(Plugin{Domain: mock.Domain("a")})
+	assert.Panics(t, func() { Register(Plugin{Domain: mock.Domain("a")}) })
+	assert.Panics(t, func() { Register(Plugin{}) })
+
+	var names []string
+	for _, p := range List() {
+		names = append(names, p.Domain.String())
+	}
+	assert.Equal(t, []string{"a", "b"}, names)
+	assert.Equal(t, []Goal{{Label: "B", Class: "b/x"}}, Get("b").Goals)
+	assert.Nil(t, Get("nonesuch"))
+
+	s, err := NewStore(StoreEnv{}, config.Store{Domain: "b"})
+	require.NoError(t, err)
+	assert.Equal(t, store, s)
+	_, err = NewStore(StoreEnv{}, config.Store{Domain: "a"})
+	assert.EqualError(t, err, "no stores for domain: a")
+	_, err = NewStore(StoreEnv{}, config.Store{Domain: "nonesuch"})
+	assert.EqualError(t, err, "domain not found: nonesuch")
+}
+
+func TestParseURL(t *testing.T) {
+	u, err := ParseURL("https://example.com/x")
+	require.NoError(t, err)
+	assert.Equal(t, "example.com", u.Host)
+	_, err = ParseURL("ftp://example.com")
+	assert.EqualError(t, err, `"ftp://example.com": unsupported scheme`)
+}
@@ -8,12 +8,16 @@ import (
 	"path"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/korrel8r/korrel8r/internal/pkg/must"
 	"github.com/korrel8r/korrel8r/pkg/korrel8r"
 	"github.com/korrel8r/korrel8r/pkg/korrel8r/impl"
 	"github.com/korrel8r/korrel8r/pkg/openshift/console"
 	"golang.org/x/exp/slices"
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
@@ -137,15 +141,17 @@ func NewStore(c client.Client, cfg *rest.Config) (*Store, error) {
 
 func (Store) Domain() korrel8r.Domain { return Domain }
 
-func (s *Store) Get(ctx context.Context, query korrel8r.Query, result korrel8r.Appender) (err error) {
+// Get objects selected by query.
+// Event objects are filtered by timestamp to satisfy the constraint, other objects have no useful timestamp.
+func (s *Store) Get(ctx context.Context, query korrel8r.Query, constraint *korrel8r.Constraint, result korrel8r.Appender) (err error) {
 	q, err := impl.TypeAssert[*Query](query)
 	if err != nil {
 		return err
 	}
 	if q.Name != "" { // Request for single object.
-		return s.getObject(ctx, q, result)
+		return s.getObject(ctx, q, constraint, result)
 	} else {
-		return s.getList(ctx, q, result)
+		return s.getList(ctx, q, constraint, result)
 	}
 }
 
@@ -155,7 +161,7 @@ func setMeta(o Object) Object {
 	return o
 }
 
-func (s *Store) getObject(ctx context.Context, q *Query, result korrel8r.Appender) error {
+func (s *Store) getObject(ctx context.Context, q *Query, constraint *korrel8r.Constraint, result korrel8r.Appender) error {
 	scheme := s.c.Scheme()
 	o, err := scheme.New(q.GroupVersionKind)
 	if err != nil {
@@ -166,14 +172,19 @@ func (s *Store) getObject(ctx context.Context, q *Query, result korrel8r.Appende
 		return fmt.Errorf("invalid client.Object: %T", o)
 	}
 	err = s.c.Get(ctx, q.NamespacedName, co)
+	if apierrors.IsNotFound(err) {
+		return nil // Not an error, just an empty result.
+	}
 	if err != nil {
 		return err
 	}
-	result.Append(setMeta(co))
+	if timeOK(co, constraint) && !constraint.LimitReached(0) {
+		result.Append(setMeta(co))
+	}
 	return nil
 }
 
-func (s *Store) getList(ctx context.Context, q *Query, result korrel8r.Appender) error {
+func (s *Store) getList(ctx context.Context, q *Query, constraint *korrel8r.Constraint, result korrel8r.Appender) (err error) {
 	gvk := q.GroupVersionKind
 	gvk.Kind = gvk.Kind + "List"
 	o, err := s.c.Scheme().New(gvk)
@@ -203,12 +214,49 @@ func (s *Store) getList(ctx context.Context, q *Query, result korrel8r.Appender)
 		}
 	}()
 	items := reflect.ValueOf(list).Elem().FieldByName("Items")
-	for i := 0; i < items.Len(); i++ {
-		result.Append(setMeta(items.Index(i).Addr().Interface().(client.Object)))
+	n := 0
+	for i := 0; i < items.Len() && !constraint.LimitReached(n); i++ {
+		o := items.Index(i).Addr().Interface().(client.Object)
+		if timeOK(o, constraint) {
+			result.Append(setMeta(o))
+			n++
+		}
 	}
 	return nil
 }
 
+// timeOK returns true if o has no timestamp, or its timestamp is inside the constraint interval.
+func timeOK(o client.Object, constraint *korrel8r.Constraint) bool {
+	if t := eventTime(o); !t.IsZero() {
+		return constraint.CompareTime(t) == 0
+	}
+	return true
+}
+
+// eventTime returns the time of the most recent occurrence of an event, zero if o is not an event.
+func eventTime(o client.Object) time.Time {
+	switch e := o.(type) {
+	case *corev1.Event:
+		for _, t := range []time.Time{e.LastTimestamp.Time, e.EventTime.Time, e.FirstTimestamp.Time} {
+			if !t.IsZero() {
+				return t
+			}
+		}
+		return e.CreationTimestamp.Time
+	case *eventsv1.Event:
+		if e.Series != nil && !e.Series.LastObservedTime.IsZero() {
+			return e.Series.LastObservedTime.Time
+		}
+		for _, t := range []time.Time{e.EventTime.Time, e.DeprecatedLastTimestamp.Time} {
+			if !t.IsZero() {
+				return t
+			}
+		}
+		return e.CreationTimestamp.Time
+	}
+	return time.Time{}
+}
+
 func (s *Store) resource(gvk schema.GroupVersionKind) (schema.GroupVersionResource, error) {
 	rm, err := s.c.RESTMapper().RESTMappings(gvk.GroupKind(), gvk.Version)
 	if err != nil {
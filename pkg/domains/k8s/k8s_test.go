@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/korrel8r/korrel8r/internal/pkg/must"
 	"github.com/korrel8r/korrel8r/pkg/korrel8r"
@@ -81,12 +82,13 @@ func TestStore_Get(t *testing.T) {
 		want []types.NamespacedName
 	}{
 		{Query{GroupVersionKind: podGVK, NamespacedName: fred}, []types.NamespacedName{fred}},
+		{Query{GroupVersionKind: podGVK, NamespacedName: types.NamespacedName{Namespace: "x", Name: "missing"}}, nil},
 		{Query{GroupVersionKind: podGVK, NamespacedName: types.NamespacedName{Namespace: "x"}}, []types.NamespacedName{fred, barney}},
 		{Query{GroupVersionKind: podGVK, Labels: client.MatchingLabels{"app": "foo"}}, []types.NamespacedName{fred, wilma}},
 	} {
 		t.Run(fmt.Sprintf("%#v", x.q), func(t *testing.T) {
 			var result korrel8r.ListResult
-			err = store.Get(context.Background(), &x.q, &result)
+			err = store.Get(context.Background(), &x.q, nil, &result)
 			require.NoError(t, err)
 			var got []types.NamespacedName
 			for _, v := range result {
@@ -99,6 +101,46 @@ func TestStore_Get(t *testing.T) {
 	// Need to validate labels and all get variations on fake client or env test...
 }
 
+func TestStore_GetConstraint(t *testing.T) {
+	t0 := time.Now().Truncate(time.Second)
+	t1, t2 := t0.Add(time.Minute), t0.Add(2*time.Minute)
+	event := func(name string, when time.Time) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta:    metav1.ObjectMeta{Name: name, Namespace: "x"},
+			LastTimestamp: metav1.NewTime(when),
+		}
+	}
+	c := fake.NewClientBuilder().
+		WithRESTMapper(testrestmapper.TestOnlyStaticRESTMapper(scheme.Scheme)).
+		WithObjects(event("early", t0), event("middle", t1), event("late", t2)).
+		Build()
+	store, err := NewStore(c, &rest.Config{})
+	require.NoError(t, err)
+	one := uint(1)
+	for _, x := range []struct {
+		name string
+		c    *korrel8r.Constraint
+		want []string
+	}{
+		{"none", nil, []string{"early", "middle", "late"}},
+		{"start", &korrel8r.Constraint{Start: &t1}, []string{"middle", "late"}},
+		{"end", &korrel8r.Constraint{End: &t1}, []string{"early", "middle"}},
+		{"interval", &korrel8r.Constraint{Start: &t1, End: &t1}, []string{"middle"}},
+		{"limit", &korrel8r.Constraint{End: &t1, Limit: &one}, []string{"early"}}, // List is sorted by name.
+	} {
+		t.Run(x.name, func(t *testing.T) {
+			var result korrel8r.ListResult
+			q := NewQuery(ClassOf(&corev1.Event{}), "x", "", nil, nil)
+			require.NoError(t, store.Get(context.Background(), q, x.c, &result))
+			var got []string
+			for _, v := range result {
+				got = append(got, v.(Object).GetName())
+			}
+			assert.ElementsMatch(t, x.want, got)
+		})
+	}
+}
+
 func TestStore_QueryToConsoleURL(t *testing.T) {
 	s, err := NewStore(fake.NewClientBuilder().
 		WithRESTMapper(testrestmapper.TestOnlyStaticRESTMapper(scheme.Scheme)).
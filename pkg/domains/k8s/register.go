@@ -0,0 +1,35 @@
+package k8s
+
+import (
+	"github.com/korrel8r/korrel8r/pkg/config"
+	"github.com/korrel8r/korrel8r/pkg/domains"
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func init() {
+	domains.Register(domains.Plugin{
+		Domain:       Domain,
+		NewStore:     newStore,
+		ConsolePaths: []string{"/k8s", "/search"},
+		Goals:        []domains.Goal{{Label: "Events", Class: "k8s/Event"}},
+	})
+}
+
+// newStore uses the API server at sc.URL if set, otherwise the current cluster.
+func newStore(env domains.StoreEnv, sc config.Store) (korrel8r.Store, error) {
+	cfg := env.RESTConfig
+	if sc.URL != "" {
+		cfg = &rest.Config{
+			Host:            sc.URL,
+			TLSClientConfig: rest.TLSClientConfig{CAFile: sc.CertificateAuthority},
+			BearerTokenFile: sc.BearerTokenFile,
+		}
+	}
+	c, err := client.New(cfg, client.Options{})
+	if err != nil {
+		return nil, err
+	}
+	return NewStore(c, cfg)
+}
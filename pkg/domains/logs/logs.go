@@ -107,27 +107,26 @@ func init() {
 func (q *Query) String() string        { return q.LogQL }
 func (q *Query) Class() korrel8r.Class { return Class(q.LogType) }
 
-func (q *Query) plainURL() *url.URL {
+func (q *Query) plainURL(constraint *korrel8r.Constraint) *url.URL {
 	v := url.Values{}
 	v.Add("query", q.LogQL)
 	v.Add("direction", "forward")
-	// TODO constraint inside query
-	// if constraint != nil {
-	// 	if constraint.Limit != nil {
-	// 		v.Add("limit", fmt.Sprintf("%v", *constraint.Limit))
-	// 	}
-	// 	if constraint.Start != nil {
-	// 		v.Add("start", fmt.Sprintf("%v", constraint.Start.UnixNano()))
-	// 	}
-	// 	if constraint.End != nil {
-	// 		v.Add("end", fmt.Sprintf("%v", constraint.End.UnixNano()))
-	// 	}
-	// }
+	if constraint != nil {
+		if constraint.Limit != nil {
+			v.Add("limit", fmt.Sprintf("%v", *constraint.Limit))
+		}
+		if constraint.Start != nil {
+			v.Add("start", fmt.Sprintf("%v", constraint.Start.UnixNano()))
+		}
+		if constraint.End != nil {
+			v.Add("end", fmt.Sprintf("%v", constraint.End.UnixNano()))
+		}
+	}
 	return &url.URL{Path: "/loki/api/v1/query_range", RawQuery: v.Encode()}
 }
 
-func (q *Query) lokiStackURL() *url.URL {
-	u := q.plainURL()
+func (q *Query) lokiStackURL(constraint *korrel8r.Constraint) *url.URL {
+	u := q.plainURL(constraint)
 	if q.LogType == "" {
 		q.LogType = Application.String()
 	}
@@ -138,7 +137,7 @@ func (q *Query) lokiStackURL() *url.URL {
 type Store struct {
 	c        *http.Client
 	base     *url.URL
-	queryURL func(*Query) *url.URL
+	queryURL func(*Query, *korrel8r.Constraint) *url.URL
 }
 
 func (Store) Domain() korrel8r.Domain { return Domain }
@@ -153,14 +152,18 @@ func NewPlainLokiStore(base *url.URL, c *http.Client) (*Store, error) {
 	return &Store{c: c, base: base, queryURL: (*Query).plainURL}, nil
 }
 
-func (s *Store) Get(ctx context.Context, query korrel8r.Query, result korrel8r.Appender) error {
+func (s *Store) Get(ctx context.Context, query korrel8r.Query, constraint *korrel8r.Constraint, result korrel8r.Appender) error {
 	q, err := impl.TypeAssert[*Query](query)
 	if err != nil {
 		return err
 	}
-	u := s.base.ResolveReference(s.queryURL(q))
+	u := s.base.ResolveReference(s.queryURL(q, constraint))
 
-	resp, err := s.c.Get(u.String())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.c.Do(req)
 	if err != nil {
 		return fmt.Errorf("%w: %v", err, u)
 	}
@@ -35,7 +35,7 @@ func TestStore_Get_PlainLoki(t *testing.T) {
 	}
 	q := &Query{LogQL: `{test="logs"}`}
 	result := korrel8r.NewListResult()
-	require.NoError(t, s.Get(ctx, q, result))
+	require.NoError(t, s.Get(ctx, q, nil, result))
 	assert.Equal(t, want, result.List())
 }
 
@@ -62,7 +62,7 @@ func TestLokiStackStore_Get(t *testing.T) {
 	var result korrel8r.ListResult
 	assert.Eventually(t, func() bool {
 		result = nil
-		err = s.Get(ctx, q, &result)
+		err = s.Get(ctx, q, nil, &result)
 		require.NoError(t, err)
 		t.Logf("waiting for 4 logs, got %v. %v%v", len(result), s, q)
 		return len(result) >= 3
@@ -79,7 +79,6 @@ func TestLokiStackStore_Get(t *testing.T) {
 }
 
 func TestStoreGet_Constraint(t *testing.T) {
-	t.Skip("TODO re-enable when constraints are implemented properly")
 	t.Parallel()
 	l := test.RequireLokiServer(t)
 
@@ -114,8 +113,24 @@ func TestStoreGet_Constraint(t *testing.T) {
 	} {
 		t.Run(strconv.Itoa(n), func(t *testing.T) {
 			var result korrel8r.ListResult
-			assert.NoError(t, s.Get(ctx, x.q, &result))
+			assert.NoError(t, s.Get(ctx, x.q, x.c, &result))
 			assert.Equal(t, x.want, result.List())
 		})
 	}
 }
+
+func TestQuery_PlainURLConstraint(t *testing.T) {
+	q := &Query{LogQL: `{test="logs"}`}
+	start, end := time.Unix(1, 0), time.Unix(2, 0)
+	limit := uint(10)
+	u := q.plainURL(&korrel8r.Constraint{Limit: &limit, Start: &start, End: &end})
+	v := u.Query()
+	assert.Equal(t, "10", v.Get("limit"))
+	assert.Equal(t, "1000000000", v.Get("start"))
+	assert.Equal(t, "2000000000", v.Get("end"))
+
+	v = q.plainURL(nil).Query()
+	for _, k := range []string{"limit", "start", "end"} {
+		assert.False(t, v.Has(k), k)
+	}
+}
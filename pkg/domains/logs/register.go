@@ -0,0 +1,50 @@
+package logs
+
+import (
+	"fmt"
+
+	"github.com/korrel8r/korrel8r/pkg/config"
+	"github.com/korrel8r/korrel8r/pkg/domains"
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+)
+
+// Store kinds, see config.Store.Kind
+const (
+	KindLokiStack = "lokistack" // LokiStack gateway at URL.
+	KindLoki      = "loki"      // Plain Loki at URL.
+)
+
+func init() {
+	domains.Register(domains.Plugin{
+		Domain:       Domain,
+		NewStore:     newStore,
+		ConsolePaths: []string{"/monitoring/logs"},
+		Goals:        []domains.Goal{{Label: "Logs", Class: "logs/infrastructure"}},
+	})
+}
+
+func newStore(env domains.StoreEnv, sc config.Store) (korrel8r.Store, error) {
+	switch kind := sc.KindOrDefault(KindLokiStack); kind {
+	case config.KindOpenshift:
+		c, err := env.Client()
+		if err != nil {
+			return nil, err
+		}
+		return NewOpenshiftLokiStackStore(env.Context, c, env.RESTConfig)
+	case KindLokiStack, KindLoki:
+		hc, err := env.HTTPClient(sc)
+		if err != nil {
+			return nil, err
+		}
+		u, err := domains.ParseURL(sc.URL)
+		if err != nil {
+			return nil, err
+		}
+		if kind == KindLoki {
+			return NewPlainLokiStore(u, hc)
+		}
+		return NewLokiStackStore(u, hc)
+	default:
+		return nil, fmt.Errorf("unknown store kind for domain %v: %v", Domain, kind)
+	}
+}
@@ -87,17 +87,25 @@ func NewStore(base *url.URL, hc *http.Client) (*Store, error) {
 
 func (s *Store) Domain() korrel8r.Domain { return Domain }
 
-func (s *Store) Get(ctx context.Context, query korrel8r.Query, result korrel8r.Appender) error {
+// Get evaluates the query at constraint.End if set, at the current time otherwise.
+func (s *Store) Get(ctx context.Context, query korrel8r.Query, constraint *korrel8r.Constraint, result korrel8r.Appender) error {
 	q, err := impl.TypeAssert[*Query](query)
 	if err != nil {
 		return err
 	}
-	value, _, err := s.api.Query(ctx, q.PromQL, time.Now())
+	when := time.Now()
+	if constraint != nil && constraint.End != nil {
+		when = *constraint.End
+	}
+	value, _, err := s.api.Query(ctx, q.PromQL, when)
 	if err != nil {
 		return err
 	}
 	if values, ok := value.(model.Vector); ok {
-		for _, v := range values {
+		for i, v := range values {
+			if constraint.LimitReached(i) {
+				break
+			}
 			result.Append(v)
 		}
 	} else {
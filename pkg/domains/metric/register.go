@@ -0,0 +1,44 @@
+package metric
+
+import (
+	"fmt"
+
+	"github.com/korrel8r/korrel8r/pkg/config"
+	"github.com/korrel8r/korrel8r/pkg/domains"
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+)
+
+// KindPrometheus is a Prometheus store at URL, see config.Store.Kind
+const KindPrometheus = "prometheus"
+
+func init() {
+	domains.Register(domains.Plugin{
+		Domain:       Domain,
+		NewStore:     newStore,
+		ConsolePaths: []string{"/monitoring/query-browser"},
+		Goals:        []domains.Goal{{Label: "Metrics", Class: "metric/metric"}},
+	})
+}
+
+func newStore(env domains.StoreEnv, sc config.Store) (korrel8r.Store, error) {
+	switch kind := sc.KindOrDefault(KindPrometheus); kind {
+	case config.KindOpenshift:
+		c, err := env.Client()
+		if err != nil {
+			return nil, err
+		}
+		return NewOpenshiftStore(env.Context, c, env.RESTConfig)
+	case KindPrometheus:
+		hc, err := env.HTTPClient(sc)
+		if err != nil {
+			return nil, err
+		}
+		u, err := domains.ParseURL(sc.URL)
+		if err != nil {
+			return nil, err
+		}
+		return NewStore(u, hc)
+	default:
+		return nil, fmt.Errorf("unknown store kind for domain %v: %v", Domain, kind)
+	}
+}
@@ -0,0 +1,268 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/korrel8r/korrel8r/pkg/graph"
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+	"github.com/korrel8r/korrel8r/pkg/unique"
+	"go.uber.org/multierr"
+	"golang.org/x/exp/slices"
+)
+
+// Request describes a correlation.
+//
+// The start objects are the results of Queries plus Objects, all must belong to the Start class.
+// If Goal is set, correlate along paths from Start to Goal, otherwise correlate the neighbourhood
+// of Start up to Depth.
+// If Reverse is set, Start is treated as a symptom: find classes within Depth that have rules leading to Start.
+type Request struct {
+	Start         korrel8r.Class       // Start class, may be nil if Queries is not empty.
+	Queries       []korrel8r.Query     // Queries for start objects.
+	Objects       []korrel8r.Object    // Start objects.
+	Goal          korrel8r.Class       // Goal class, nil for a neighbourhood correlation.
+	Depth         int                  // Depth of neighbourhood, used if Goal is nil.
+	Reverse       bool                 // Reverse neighbourhood of Start, Goal must be nil.
+	ShortestPaths bool                 // Follow only the cheapest paths from Start to Goal, see Engine.Cost.
+	KShortest     int                  // If > 0, follow only the k cheapest paths from Start to Goal.
+	MaxPathLength int                  // Max number of rules on a path from Start to Goal, 0 means no limit.
+	MaxPaths      int                  // Max number of paths from Start to Goal, 0 means no limit.
+	RulesOnly     bool                 // Graph the rules without getting any results.
+	Constraint    *korrel8r.Constraint // Constraint for all rules and stores, may be nil.
+	Timeout       time.Duration        // Timeout for the whole correlation, 0 means no timeout.
+	Limits        Limits               // Limits on fan-out, see Limits.
+	// Stores selects a single store by name for some domains, see Follower.Stores.
+	Stores map[string]string
+}
+
+// Correlation is the result of a correlation Request.
+//
+// More start objects can be added to a correlation by Add, which updates the graph incrementally.
+type Correlation struct {
+	Start, Goal korrel8r.Class // Goal is nil for a neighbourhood correlation.
+	// Graph of classes and rules, nodes and lines hold the results and queries.
+	Graph *graph.Graph
+	// Failures while following rules.
+	Failures Failures
+	// Reverse is true for a reverse correlation.
+	// Graph contains the lines leading to Start, and inverse lines from Start that returned results.
+	Reverse bool
+
+	engine   *Engine
+	request  Request
+	paths    *graph.Graph // Graph of rules to follow, Graph is the subset of paths with results.
+	reverse  *graph.Graph // Reverse neighbourhood of Start, nil if not Reverse.
+	follower *Follower
+	recorded unique.Set[string] // Line queries already recorded in Engine stats.
+}
+
+// Correlate performs a correlation and returns the resulting graph.
+//
+// Store failures do not cause an error, they are reported in Correlation.Failures.
+// If a store fails or times out, the correlation continues with the results it has,
+// and affected nodes and lines are marked Incomplete.
+// Start queries that fail or time out are also reported in Failures, and the start node is marked Incomplete.
+// An error is returned if the request is invalid, or if every start query fails.
+func (e *Engine) Correlate(ctx context.Context, r Request) (*Correlation, error) {
+	if r.Start == nil && len(r.Queries) > 0 {
+		r.Start = r.Queries[0].Class()
+	}
+	if r.Start == nil {
+		return nil, errors.New("no start class")
+	}
+	if r.Reverse && r.Goal != nil {
+		return nil, errors.New("reverse correlation cannot have a goal")
+	}
+	for domain := range r.Stores {
+		if len(e.selectStores(domain, r.Stores)) == 0 {
+			return nil, fmt.Errorf("store not found: %v", StoreID(domain, r.Stores[domain]))
+		}
+	}
+	c := &Correlation{Start: r.Start, Goal: r.Goal, Reverse: r.Reverse, engine: e, request: r, recorded: unique.Set[string]{}}
+	c.follower = e.Follower(ctx, r.Constraint)
+	c.follower.Stores = r.Stores
+	c.follower.Limits = r.Limits
+	c.paths = e.Graph()
+	if r.Goal != nil { // Paths from start to goal.
+		cost := func(l *graph.Line) float64 { return e.Cost(l.Rule) }
+		switch {
+		case r.KShortest > 0:
+			c.paths = c.paths.KShortestPaths(r.Start, r.Goal, r.KShortest, cost)
+		case r.ShortestPaths:
+			c.paths = c.paths.CheapestPaths(r.Start, r.Goal, cost)
+		default:
+			c.paths = c.paths.BoundedPaths(r.Start, r.Goal, graph.PathOptions{MaxLength: r.MaxPathLength, MaxCount: r.MaxPaths})
+		}
+	} else if r.Reverse { // Classes leading to start.
+		all := c.paths
+		c.reverse = all.ReverseNeighbours(r.Start, r.Depth)
+		// Follow inverse rules, they lead back from start to the classes that lead to it.
+		c.paths = all.Data.EmptyGraph()
+		c.reverse.EachLine(func(l *graph.Line) {
+			inverse := all.Lines(l.To().ID(), l.From().ID())
+			for inverse.Next() {
+				c.paths.SetLine(inverse.Line())
+			}
+		})
+	} else { // Neighbourhood of start.
+		c.paths = c.paths.Neighbours(r.Start, r.Depth, nil)
+	}
+	if r.RulesOnly {
+		c.Graph = c.paths
+		if c.reverse != nil {
+			c.Graph = c.reverseGraph(func(*graph.Line) bool { return true })
+		}
+		c.addStartGoal()
+		return c, nil
+	}
+	if err := c.Add(ctx, r.Queries, r.Objects); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Add adds start objects, and the results of start queries, to the correlation.
+//
+// Rules are applied only to objects that are new to their start class, queries that were already
+// sent are not repeated. New results are merged into the correlation Graph, Failures are updated.
+// The options of the original Request are used, including the Timeout for this call.
+//
+// Start queries that fail or time out on some stores are recorded in Failures, and the start node is marked Incomplete.
+// Add returns an error, and does not change the correlation, only if every start query failed on every store.
+// Add is not safe for concurrent use.
+func (c *Correlation) Add(ctx context.Context, queries []korrel8r.Query, objects []korrel8r.Object) error {
+	if c.engine == nil || c.request.RulesOnly {
+		return errors.New("cannot add to this correlation")
+	}
+	e, r := c.engine, c.request
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	start := c.paths.NodeFor(c.Start)
+	for _, q := range queries {
+		if q.Class() != c.Start {
+			return fmt.Errorf("query class %v does not match start class %v", korrel8r.ClassName(q.Class()), korrel8r.ClassName(c.Start))
+		}
+	}
+	type queryResults struct {
+		query   korrel8r.Query
+		results []storeResult
+	}
+	var (
+		got      []queryResults
+		failures error // Errors other than timeouts.
+		ok       bool  // At least one store Get did not fail.
+	)
+	for _, q := range queries {
+		if _, ok := start.QueryCounts.Get(q); ok {
+			continue // Already have the results.
+		}
+		stores := e.selectStores(c.Start.Domain().String(), r.Stores)
+		if len(stores) == 0 {
+			return fmt.Errorf("store not found: %v", c.Start.Domain())
+		}
+		results := getEach(ctx, stores, q, r.Constraint)
+		for _, sr := range results {
+			switch {
+			case sr.err == nil, errors.Is(sr.err, context.DeadlineExceeded):
+				ok = true
+			default:
+				failures = multierr.Append(failures, fmt.Errorf("store %v: %w", sr.id, sr.err))
+			}
+		}
+		got = append(got, queryResults{query: q, results: results})
+	}
+	if failures != nil && !ok {
+		return failures
+	}
+	for _, qr := range got {
+		for _, sr := range qr.results {
+			if sr.err != nil { // Keep partial results, as for goal stores.
+				kind := StoreFailed
+				if errors.Is(sr.err, context.DeadlineExceeded) {
+					kind = StoreTimeout
+				}
+				c.follower.fail(newStartFailure(kind, c.Start, qr.query, sr.id, sr.err))
+				start.Incomplete = true
+			}
+			r.Limits.appendResults(start, graph.Origin{Query: qr.query}, sr.objects)
+			start.QueryCounts.Add(qr.query, sr.id, len(sr.objects))
+		}
+	}
+	start.Result.Append(objects...)
+
+	c.follower.Context = ctx
+	if err := c.paths.Traverse(c.follower.Traverse); err != nil {
+		return err
+	}
+	c.Failures = c.follower.Failures
+	if e.stats != nil {
+		// Only learn from rules with a goal store, rules with no store can't return results.
+		// Only record each query once, lines keep their queries from previous calls to Add.
+		e.stats.record(c.paths.Select(func(l *graph.Line) bool { return len(e.stores[l.Rule.Goal().Domain().String()]) > 0 }),
+			func(l *graph.Line, query string) bool { return !c.recorded.Add(fmt.Sprintf("%v %v", l.ID(), query)) })
+	}
+	c.Graph = c.paths.Select(func(l *graph.Line) bool { // Remove lines with no results, unless incomplete or truncated.
+		return l.QueryCounts.Total() > 0 || l.Incomplete || l.Truncated
+	})
+	if r.Goal != nil {
+		// Only include start->goal paths, remove dead-ends.
+		c.Graph = c.Graph.BoundedPaths(r.Start, r.Goal, graph.PathOptions{MaxLength: r.MaxPathLength, MaxCount: r.MaxPaths})
+	}
+	if c.reverse != nil {
+		c.Graph = c.reverseGraph(func(l *graph.Line) bool { return l.QueryCounts.Total() > 0 || l.Incomplete || l.Truncated })
+	}
+	c.addStartGoal()
+	return nil
+}
+
+// Request returns the request for the correlation.
+// A correlation loaded by Engine.LoadCorrelation has the options of the saved request, but no start queries or objects.
+func (c *Correlation) Request() Request { return c.request }
+
+// reverseGraph returns the reverse neighbourhood plus the inverse lines where keep is true.
+func (c *Correlation) reverseGraph(keep func(*graph.Line) bool) *graph.Graph {
+	g := c.reverse.Select(func(*graph.Line) bool { return true })
+	c.paths.EachLine(func(l *graph.Line) {
+		if keep(l) {
+			g.SetLine(l)
+		}
+	})
+	return g
+}
+
+// addStartGoal includes start and goal nodes in the graph, even if they are empty.
+func (c *Correlation) addStartGoal() {
+	for _, class := range []korrel8r.Class{c.Start, c.Goal} {
+		if class != nil {
+			if n := c.Graph.NodeFor(class); c.Graph.Node(n.ID()) == nil {
+				c.Graph.AddNode(n)
+			}
+		}
+	}
+}
+
+// NodeResult summarizes the objects and queries for a class in a Correlation.
+type NodeResult struct {
+	Class      korrel8r.Class
+	Objects    []korrel8r.Object
+	Queries    []graph.QueryCount // Queries sorted by decreasing count.
+	Incomplete bool               // Some queries failed or timed out, Objects may be incomplete.
+	Truncated  bool               // Some objects or queries were dropped by Request.Limits.
+}
+
+// Nodes returns a summary of the results for each non-empty or truncated node in the graph, sorted by class name.
+func (c *Correlation) Nodes() (nodes []NodeResult) {
+	c.Graph.EachNode(func(n *graph.Node) {
+		if objects := n.Result.List(); len(objects) > 0 || len(n.QueryCounts) > 0 || n.Truncated {
+			nodes = append(nodes, NodeResult{Class: n.Class, Objects: objects, Queries: n.QueryCounts.Sort(), Incomplete: n.Incomplete, Truncated: n.Truncated})
+		}
+	})
+	slices.SortFunc(nodes, func(a, b NodeResult) bool { return korrel8r.ClassName(a.Class) < korrel8r.ClassName(b.Class) })
+	return nodes
+}
@@ -0,0 +1,527 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/korrel8r/korrel8r/internal/pkg/test/mock"
+	"github.com/korrel8r/korrel8r/pkg/graph"
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// follow returns a rule function that queries s for the goal object with the data of the start object,
+// for example "a:1" leads to "b:1".
+func follow(s mock.Store, goal string) mock.ApplyFunc {
+	return func(start korrel8r.Object, _ *korrel8r.Constraint) (korrel8r.Query, error) {
+		return s.NewQuery(goal + ":" + start.(mock.Object).Data()), nil
+	}
+}
+
+func correlateEngine() (*Engine, mock.Store) {
+	s := mock.Store{}
+	e := New()
+	e.AddDomain(mock.Domain(""), s)
+	e.AddRules(
+		mock.NewRule("ab", "a", "b", follow(s, "b")),
+		mock.NewRule("bc", "b", "c", follow(s, "c")),
+		mock.NewRule("ax", "a", "x", func(korrel8r.Object, *korrel8r.Constraint) (korrel8r.Query, error) {
+			return s.NewQuery(), nil // Empty result.
+		}),
+		mock.NewRule("cd", "c", "d", follow(s, "d")),
+	)
+	return e, s
+}
+
+func nodeClasses(c *Correlation) (classes []korrel8r.Class) {
+	for _, n := range c.Nodes() {
+		classes = append(classes, n.Class)
+	}
+	return classes
+}
+
+func TestEngine_Correlate_Goal(t *testing.T) {
+	e, s := correlateEngine()
+	c, err := e.Correlate(context.Background(), Request{Queries: []korrel8r.Query{s.NewQuery("a:1")}, Goal: mock.Class("c")})
+	require.NoError(t, err)
+	assert.Equal(t, mock.Class("a"), c.Start)
+	assert.Empty(t, c.Failures)
+	assert.Equal(t, []korrel8r.Class{mock.Class("a"), mock.Class("b"), mock.Class("c")}, nodeClasses(c))
+	nodes := c.Nodes()
+	assert.Equal(t, mock.Objects("c:1"), nodes[2].Objects)
+	assert.Equal(t, []graph.QueryCount{{Query: mock.Query("c:1"), Count: 1, Stores: map[string]int{"": 1}}}, nodes[2].Queries)
+}
+
+func TestEngine_Correlate_Neighbours(t *testing.T) {
+	e, _ := correlateEngine()
+	for _, x := range []struct {
+		depth int
+		want  []korrel8r.Class
+	}{
+		{1, []korrel8r.Class{mock.Class("a"), mock.Class("b")}},
+		{2, []korrel8r.Class{mock.Class("a"), mock.Class("b"), mock.Class("c")}},
+	} {
+		c, err := e.Correlate(context.Background(), Request{Start: mock.Class("a"), Objects: mock.Objects("a:1"), Depth: x.depth})
+		require.NoError(t, err)
+		assert.Equal(t, x.want, nodeClasses(c), "depth %v", x.depth)
+		// Lines with no results are removed.
+		for _, l := range c.Graph.AllLines() {
+			assert.NotEqual(t, "ax", l.Rule.String())
+		}
+	}
+}
+
+func TestEngine_Correlate_RulesOnly(t *testing.T) {
+	e, _ := correlateEngine()
+	c, err := e.Correlate(context.Background(), Request{Start: mock.Class("a"), Depth: 1, RulesOnly: true})
+	require.NoError(t, err)
+	var rules []string
+	for _, l := range c.Graph.AllLines() {
+		rules = append(rules, l.Rule.String())
+	}
+	assert.ElementsMatch(t, []string{"ab", "ax"}, rules)
+}
+
+func TestEngine_Correlate_Paths(t *testing.T) {
+	e, _ := correlateEngine()
+	e.AddRules(mock.NewRule("ad", "a", "d", func(start korrel8r.Object, _ *korrel8r.Constraint) (korrel8r.Query, error) {
+		return mock.Query("d:1"), nil
+	}))
+	for _, x := range []struct {
+		name string
+		r    Request
+		want []string
+	}{
+		{"all", Request{}, []string{"ab", "ad", "bc", "cd"}},
+		{"max length", Request{MaxPathLength: 2}, []string{"ad"}},
+		{"max paths", Request{MaxPaths: 1}, []string{"ad"}},
+		{"k shortest", Request{KShortest: 1}, []string{"ad"}},
+		{"k shortest 2", Request{KShortest: 2}, []string{"ab", "ad", "bc", "cd"}},
+	} {
+		t.Run(x.name, func(t *testing.T) {
+			x.r.Start, x.r.Goal, x.r.RulesOnly = mock.Class("a"), mock.Class("d"), true
+			c, err := e.Correlate(context.Background(), x.r)
+			require.NoError(t, err)
+			var rules []string
+			for _, l := range c.Graph.AllLines() {
+				rules = append(rules, l.Rule.String())
+			}
+			assert.ElementsMatch(t, x.want, rules)
+		})
+	}
+}
+
+func TestEngine_Correlate_Errors(t *testing.T) {
+	e, s := correlateEngine()
+	_, err := e.Correlate(context.Background(), Request{})
+	assert.EqualError(t, err, "no start class")
+	_, err = e.Correlate(context.Background(), Request{Start: mock.Class("b"), Queries: []korrel8r.Query{s.NewQuery("a:1")}})
+	assert.EqualError(t, err, "query class /a does not match start class /b")
+}
+
+// hangStore returns some results then hangs until the context is done, for queries with "hang" in them.
+type hangStore struct{ mock.Store }
+
+func (s hangStore) Get(ctx context.Context, q korrel8r.Query, c *korrel8r.Constraint, r korrel8r.Appender) error {
+	if err := s.Store.Get(ctx, q, c, r); err != nil || !strings.Contains(string(q.(mock.Query)), "hang") {
+		return err
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func timeoutEngine() (*Engine, Request) {
+	s := hangStore{Store: mock.Store{}}
+	e := New()
+	e.AddDomain(mock.Domain(""), s)
+	e.AddRules(
+		mock.NewRule("ab", "a", "b", func(korrel8r.Object, *korrel8r.Constraint) (korrel8r.Query, error) {
+			return s.NewQuery("b:hang"), nil
+		}),
+		mock.NewRule("ac", "a", "c", func(korrel8r.Object, *korrel8r.Constraint) (korrel8r.Query, error) {
+			return s.NewQuery("c:1"), nil
+		}))
+	return e, Request{Start: mock.Class("a"), Objects: mock.Objects("a:1"), Depth: 1}
+}
+
+func TestEngine_Correlate_StoreTimeout(t *testing.T) {
+	e, r := timeoutEngine()
+	e.SetStoreTimeout("", 10*time.Millisecond)
+	c, err := e.Correlate(context.Background(), r)
+	require.NoError(t, err)
+	if assert.Len(t, c.Failures, 1) {
+		assert.Equal(t, StoreTimeout, c.Failures[0].Kind)
+		assert.Equal(t, "ab", c.Failures[0].Rule)
+	}
+	nodes := c.Nodes()
+	if assert.Len(t, nodes, 3) {
+		// Partial results are kept.
+		assert.Equal(t, NodeResult{Class: mock.Class("b"), Objects: mock.Objects("b:hang"),
+			Queries: []graph.QueryCount{{Query: mock.Query("b:hang"), Count: 1, Stores: map[string]int{"": 1}}}, Incomplete: true}, nodes[1])
+		assert.Equal(t, NodeResult{Class: mock.Class("c"), Objects: mock.Objects("c:1"),
+			Queries: []graph.QueryCount{{Query: mock.Query("c:1"), Count: 1, Stores: map[string]int{"": 1}}}}, nodes[2])
+	}
+}
+
+func TestEngine_Correlate_StartTimeout(t *testing.T) {
+	e, r := timeoutEngine()
+	e.SetStoreTimeout("", 10*time.Millisecond)
+	r.Objects = nil
+	r.Queries = []korrel8r.Query{e.Store("").(hangStore).NewQuery("a:1", "a:hang")}
+	c, err := e.Correlate(context.Background(), r)
+	require.NoError(t, err)
+	if assert.Len(t, c.Failures, 2) {
+		assert.Equal(t, Failures{
+			newStartFailure(StoreTimeout, mock.Class("a"), r.Queries[0], "", context.DeadlineExceeded),
+			newFailure(StoreTimeout, e.Rules()[0], mock.Query("b:hang"), "", context.DeadlineExceeded),
+		}, c.Failures)
+		assert.EqualError(t, c.Failures[0], `store timed out: start [/a] query "a:1\u0026a:hang": context deadline exceeded`)
+	}
+	// Partial start results are kept and followed.
+	a := c.Graph.NodeFor(mock.Class("a"))
+	assert.True(t, a.Incomplete)
+	assert.Equal(t, mock.Objects("a:1", "a:hang"), a.Result.List())
+	assert.Equal(t, mock.Objects("c:1"), c.Graph.NodeFor(mock.Class("c")).Result.List())
+}
+
+func TestEngine_Correlate_StartStoreFailed(t *testing.T) {
+	s := mock.Store{}
+	e := New()
+	e.AddDomain(mock.Domain(""), nil)
+	require.NoError(t, e.AddStore("", "good", s))
+	require.NoError(t, e.AddStore("", "bad", errStore{}))
+	e.AddDomain(mock.Domain("x"), s)
+	e.AddRules(mock.NewRule("ab", "a", "x/b", follow(s, "x/b")))
+	q := s.NewQuery("a:1")
+	r := Request{Start: mock.Class("a"), Goal: mock.Class("x/b"), Queries: []korrel8r.Query{q}}
+	c, err := e.Correlate(context.Background(), r)
+	require.NoError(t, err)
+	// Results from the good store are kept and followed.
+	assert.Equal(t, Failures{newStartFailure(StoreFailed, mock.Class("a"), q, "/bad", errors.New("broken"))}, c.Failures)
+	a := c.Graph.NodeFor(mock.Class("a"))
+	assert.True(t, a.Incomplete)
+	assert.Equal(t, mock.Objects("a:1"), a.Result.List())
+	assert.Equal(t, mock.Objects("x/b:1"), c.Graph.NodeFor(mock.Class("x/b")).Result.List())
+
+	// Fail if every store fails.
+	r.Stores = map[string]string{"": "bad"}
+	_, err = e.Correlate(context.Background(), r)
+	assert.EqualError(t, err, "store /bad: broken")
+}
+
+func TestEngine_Correlate_Timeout(t *testing.T) {
+	e, r := timeoutEngine()
+	r.Timeout = 10 * time.Millisecond
+	c, err := e.Correlate(context.Background(), r)
+	require.NoError(t, err)
+	// Queries after the deadline also time out, but the correlation completes.
+	assert.NotEmpty(t, c.Failures)
+	assert.Equal(t, c.Failures, c.Failures.Kind(StoreTimeout))
+	b := c.Graph.NodeFor(mock.Class("b"))
+	assert.True(t, b.Incomplete)
+	assert.Equal(t, mock.Objects("b:hang"), b.Result.List())
+}
+
+func TestEngine_Correlate_Stores(t *testing.T) {
+	u := mock.Store{"b:q": mock.Objects("b:3")}
+	x, y := mock.Store{"b:q": mock.Objects("b:1")}, mock.Store{"b:q": mock.Objects("b:1", "b:2")}
+	e := New()
+	e.AddDomain(mock.Domain(""), u)
+	require.NoError(t, e.AddStore("", "x", x))
+	require.NoError(t, e.AddStore("", "y", y))
+	e.AddRules(mock.NewRule("ab", "a", "b", func(korrel8r.Object, *korrel8r.Constraint) (korrel8r.Query, error) {
+		return mock.Query("b:q"), nil
+	}))
+	for _, tc := range []struct {
+		stores  map[string]string
+		objects []korrel8r.Object
+		count   int
+		counts  map[string]int
+	}{
+		{nil, mock.Objects("b:1", "b:2", "b:3"), 4, map[string]int{"": 1, "/x": 1, "/y": 2}},
+		{map[string]string{"": "x"}, mock.Objects("b:1"), 1, map[string]int{"/x": 1}},
+		{map[string]string{"": ""}, mock.Objects("b:3"), 1, map[string]int{"": 1}}, // Only the unnamed store.
+	} {
+		t.Run(fmt.Sprintf("%v", tc.stores), func(t *testing.T) {
+			c, err := e.Correlate(context.Background(), Request{Start: mock.Class("a"), Objects: mock.Objects("a:1"), Goal: mock.Class("b"), Stores: tc.stores})
+			require.NoError(t, err)
+			nodes := c.Nodes()
+			require.Len(t, nodes, 2)
+			// Results are merged, the count from each store is recorded.
+			assert.ElementsMatch(t, tc.objects, nodes[1].Objects)
+			assert.Equal(t, []graph.QueryCount{{Query: mock.Query("b:q"), Count: tc.count, Stores: tc.counts}}, nodes[1].Queries)
+		})
+	}
+	_, err := e.Correlate(context.Background(), Request{Start: mock.Class("a"), Stores: map[string]string{"": "z"}})
+	assert.EqualError(t, err, "store not found: /z")
+	assert.EqualError(t, e.AddStore("", "x", x), "duplicate store: /x")
+}
+
+func TestCorrelation_Add(t *testing.T) {
+	s := mock.Store{}
+	e := New()
+	e.AddDomain(mock.Domain(""), s)
+	var mu sync.Mutex
+	applied := map[string]int{} // Count rule applications by start object.
+	count := func(apply mock.ApplyFunc) mock.ApplyFunc {
+		return func(start korrel8r.Object, c *korrel8r.Constraint) (korrel8r.Query, error) {
+			mu.Lock()
+			applied[string(start.(mock.Object))]++
+			mu.Unlock()
+			return apply(start, c)
+		}
+	}
+	e.AddRules(mock.NewRule("ab", "a", "b", count(follow(s, "b"))), mock.NewRule("bc", "b", "c", count(follow(s, "c"))))
+	ctx := context.Background()
+	c, err := e.Correlate(ctx, Request{Queries: []korrel8r.Query{s.NewQuery("a:1")}, Goal: mock.Class("c")})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"a:1": 1, "b:1": 1}, applied)
+
+	require.NoError(t, c.Add(ctx, []korrel8r.Query{s.NewQuery("a:2"), s.NewQuery("a:1")}, mock.Objects("a:3")))
+	nodes := c.Nodes()
+	require.Len(t, nodes, 3)
+	assert.ElementsMatch(t, mock.Objects("c:1", "c:2", "c:3"), nodes[2].Objects)
+	assert.Len(t, nodes[2].Queries, 3)
+	// Rules were applied once to each object.
+	assert.Equal(t, map[string]int{"a:1": 1, "a:2": 1, "a:3": 1, "b:1": 1, "b:2": 1, "b:3": 1}, applied)
+
+	assert.EqualError(t, c.Add(ctx, []korrel8r.Query{s.NewQuery("b:1")}, nil), "query class /b does not match start class /a")
+
+	c, err = e.Correlate(ctx, Request{Start: mock.Class("a"), Goal: mock.Class("c"), RulesOnly: true})
+	require.NoError(t, err)
+	assert.Error(t, c.Add(ctx, nil, mock.Objects("a:1")))
+}
+
+func TestEngine_Correlate_Reverse(t *testing.T) {
+	s := mock.Store{}
+	e := New()
+	e.AddDomain(mock.Domain(""), s)
+	e.AddRules(
+		mock.NewRule("ab", "a", "b", follow(s, "b")),
+		mock.NewRule("cb", "c", "b", follow(s, "b")),
+		mock.NewRule("ba", "b", "a", follow(s, "a")), // Inverse of ab
+		mock.NewRule("bd", "b", "d", follow(s, "d")), // Not reversed
+	)
+	c, err := e.Correlate(context.Background(), Request{Start: mock.Class("b"), Objects: mock.Objects("b:1"), Depth: 1, Reverse: true})
+	require.NoError(t, err)
+	assert.True(t, c.Reverse)
+	var rules []string
+	for _, l := range c.Graph.AllLines() {
+		rules = append(rules, l.Rule.String())
+	}
+	assert.ElementsMatch(t, []string{"ab", "cb", "ba"}, rules)
+	// Only a has an inverse rule to evaluate.
+	assert.Equal(t, []korrel8r.Class{mock.Class("a"), mock.Class("b")}, nodeClasses(c))
+	assert.Equal(t, mock.Objects("a:1"), c.Nodes()[0].Objects)
+	assert.NotNil(t, c.Graph.Node(c.Graph.NodeFor(mock.Class("c")).ID()), "candidate without inverse rule")
+
+	_, err = e.Correlate(context.Background(), Request{Start: mock.Class("b"), Goal: mock.Class("a"), Reverse: true})
+	assert.EqualError(t, err, "reverse correlation cannot have a goal")
+}
+
+func TestEngine_Correlate_Provenance(t *testing.T) {
+	e, s := correlateEngine()
+	c, err := e.Correlate(context.Background(), Request{Queries: []korrel8r.Query{s.NewQuery("a:1")}, Objects: mock.Objects("a:2"), Goal: mock.Class("c")})
+	require.NoError(t, err)
+	// Chains as strings, rules can't be compared.
+	provenance := func(class, object string) (chains [][]string) {
+		for _, chain := range c.Graph.NodeFor(mock.Class(class)).Provenance(mock.Object(object)) {
+			var steps []string
+			for _, s := range chain {
+				steps = append(steps, fmt.Sprintf("%v %v %v", s.Rule, s.Query, s.Object))
+			}
+			chains = append(chains, steps)
+		}
+		return chains
+	}
+	assert.Equal(t, [][]string{{"<nil> a:1 a:1", "ab b:1 b:1", "bc c:1 c:1"}}, provenance("c", "c:1"))
+	assert.Equal(t, [][]string{{"<nil> <nil> a:2", "ab b:2 b:2"}}, provenance("b", "b:2"))
+}
+
+func TestEngine_Correlate_Limits(t *testing.T) {
+	e, s := correlateEngine()
+	start := s.NewQuery("a:1", "a:2", "a:3")
+	for _, x := range []struct {
+		limits    Limits
+		want      map[string]int // Object count by class.
+		truncated []string       // Truncated classes.
+	}{
+		{Limits{}, map[string]int{"a": 3, "b": 3, "c": 3}, nil},
+		{Limits{MaxObjects: 2}, map[string]int{"a": 2, "b": 2, "c": 2}, []string{"a"}},
+		{Limits{MaxLineQueries: 1}, map[string]int{"a": 3, "b": 1, "c": 1}, []string{"b"}},
+		{Limits{MaxQueries: 2}, map[string]int{"a": 3, "b": 2}, []string{"b", "c"}},
+	} {
+		t.Run(fmt.Sprintf("%+v", x.limits), func(t *testing.T) {
+			c, err := e.Correlate(context.Background(), Request{Queries: []korrel8r.Query{start}, Goal: mock.Class("c"), Limits: x.limits})
+			require.NoError(t, err)
+			got := map[string]int{}
+			var truncated []string
+			for _, n := range c.Nodes() {
+				if len(n.Objects) > 0 {
+					got[n.Class.String()] = len(n.Objects)
+				}
+				if n.Truncated {
+					truncated = append(truncated, n.Class.String())
+				}
+			}
+			assert.Equal(t, x.want, got)
+			assert.Equal(t, x.truncated, truncated)
+		})
+	}
+}
+
+// constrainerRule is a mock rule that narrows the constraint.
+type constrainerRule struct {
+	mock.Rule
+	constraint *korrel8r.Constraint
+}
+
+func (r constrainerRule) Constraint(_ korrel8r.Object, c *korrel8r.Constraint) (*korrel8r.Constraint, error) {
+	return c.Combine(r.constraint), nil
+}
+
+func TestEngine_Correlate_Constrainer(t *testing.T) {
+	s := mock.Store{}
+	e := New()
+	e.AddDomain(mock.Domain(""), s)
+	one, two := uint(1), uint(2)
+	var applied *korrel8r.Constraint
+	e.AddRules(
+		constrainerRule{mock.NewRule("ab", "a", "b", func(_ korrel8r.Object, c *korrel8r.Constraint) (korrel8r.Query, error) {
+			applied = c
+			return s.NewQuery("b:1", "b:2"), nil
+		}), &korrel8r.Constraint{Limit: &one}},
+		mock.NewRule("bc", "b", "c", func(start korrel8r.Object, _ *korrel8r.Constraint) (korrel8r.Query, error) {
+			return s.NewQuery("c:1."+start.(mock.Object).Data(), "c:2."+start.(mock.Object).Data()), nil
+		}),
+	)
+	// The constraint from rule ab applies to its results, and to rules that follow from them.
+	c, err := e.Correlate(context.Background(), Request{Objects: mock.Objects("a:1"), Start: mock.Class("a"), Goal: mock.Class("c"), Constraint: &korrel8r.Constraint{Limit: &two}})
+	require.NoError(t, err)
+	nodes := c.Nodes()
+	require.Len(t, nodes, 3)
+	assert.Equal(t, mock.Objects("b:1"), nodes[1].Objects)
+	assert.Equal(t, mock.Objects("c:1.1"), nodes[2].Objects)
+	// The rule is applied with its own constraint.
+	assert.Equal(t, &korrel8r.Constraint{Limit: &one}, applied)
+
+	// No queries if the rule's time window does not overlap the request's.
+	t1, t2 := time.Unix(1, 0), time.Unix(2, 0)
+	e = New()
+	e.AddDomain(mock.Domain(""), s)
+	e.AddRules(constrainerRule{mock.NewRule("ab", "a", "b", follow(s, "b")), &korrel8r.Constraint{Start: &t2}})
+	c, err = e.Correlate(context.Background(), Request{Objects: mock.Objects("a:1"), Start: mock.Class("a"), Goal: mock.Class("b"), Constraint: &korrel8r.Constraint{End: &t1}})
+	require.NoError(t, err)
+	assert.Empty(t, c.Failures)
+	assert.Empty(t, c.Graph.NodeFor(mock.Class("b")).QueryCounts)
+}
+
+// windowStore is a mock store that records the constraint of each Get.
+type windowStore struct {
+	mock.Store
+	mu  sync.Mutex
+	got []*korrel8r.Constraint
+}
+
+func (s *windowStore) Get(ctx context.Context, q korrel8r.Query, c *korrel8r.Constraint, r korrel8r.Appender) error {
+	s.mu.Lock()
+	s.got = append(s.got, c)
+	s.mu.Unlock()
+	return s.Store.Get(ctx, q, c, r)
+}
+
+// windowRule is a mock rule with a time window that depends on the start object "a:N": from N to N+1 seconds.
+type windowRule struct{ mock.Rule }
+
+func (r windowRule) Constraint(start korrel8r.Object, c *korrel8r.Constraint) (*korrel8r.Constraint, error) {
+	n, err := strconv.Atoi(start.(mock.Object).Data())
+	if err != nil {
+		return nil, err
+	}
+	begin, end := time.Unix(int64(n), 0), time.Unix(int64(n+1), 0)
+	return c.Combine(&korrel8r.Constraint{Start: &begin, End: &end}), nil
+}
+
+func TestEngine_Correlate_ConstraintWindows(t *testing.T) {
+	s := &windowStore{Store: mock.Store{}}
+	e := New()
+	e.AddDomain(mock.Domain(""), s)
+	q := s.NewQuery("b:1")
+	e.AddRules(
+		windowRule{mock.NewRule("ab", "a", "b", func(korrel8r.Object, *korrel8r.Constraint) (korrel8r.Query, error) { return q, nil })},
+		mock.NewRule("bc", "b", "c", follow(s.Store, "c")),
+	)
+	// Two starts generate the same query with disjoint windows, the query is sent with each window.
+	c, err := e.Correlate(context.Background(), Request{Objects: mock.Objects("a:1", "a:5"), Start: mock.Class("a"), Goal: mock.Class("c")})
+	require.NoError(t, err)
+	var got []string
+	for _, c := range s.got {
+		if c != nil {
+			got = append(got, fmt.Sprintf("%v-%v", c.Start.Unix(), c.End.Unix()))
+		}
+	}
+	assert.ElementsMatch(t, []string{"1-2", "5-6", "1-6"}, got, "b queries for each window, c query for the union")
+	qc, _ := c.Graph.NodeFor(mock.Class("b")).QueryCounts.Get(q)
+	assert.Equal(t, 2, qc.Count)
+}
+
+// multiRule is a mock rule that generates several queries.
+type multiRule struct {
+	mock.Rule
+	applyAll func(korrel8r.Object) []korrel8r.Query
+}
+
+func (r multiRule) ApplyAll(start korrel8r.Object, _ *korrel8r.Constraint) ([]korrel8r.Query, error) {
+	return r.applyAll(start), nil
+}
+
+func TestEngine_Correlate_MultiApplier(t *testing.T) {
+	s := mock.Store{}
+	e := New()
+	e.AddDomain(mock.Domain(""), s)
+	e.AddRules(multiRule{mock.NewRule("ab", "a", "b", nil), func(start korrel8r.Object) []korrel8r.Query {
+		return []korrel8r.Query{s.NewQuery("b:0"), s.NewQuery("b:" + start.(mock.Object).Data())}
+	}})
+	c, err := e.Correlate(context.Background(), Request{Objects: mock.Objects("a:1", "a:2"), Start: mock.Class("a"), Goal: mock.Class("b")})
+	require.NoError(t, err)
+	nodes := c.Nodes()
+	require.Len(t, nodes, 2)
+	assert.ElementsMatch(t, mock.Objects("b:0", "b:1", "b:2"), nodes[1].Objects)
+	// The duplicate query b:0 is only evaluated once, but has both start objects as parents.
+	lines := c.Graph.AllLines()
+	require.Len(t, lines, 1)
+	assert.Len(t, lines[0].QueryCounts, 3)
+	qc, _ := lines[0].QueryCounts.Get(mock.Query("b:0"))
+	assert.Equal(t, 1, qc.Count)
+	assert.ElementsMatch(t, mock.Objects("a:1", "a:2"), lines[0].Parents[korrel8r.JSONString(mock.Query("b:0"))])
+}
+
+func TestApplyAll(t *testing.T) {
+	s := mock.Store{}
+	multi := multiRule{mock.NewRule("ab", "a", "b", nil), func(korrel8r.Object) []korrel8r.Query {
+		return []korrel8r.Query{s.NewQuery("b:1"), s.NewQuery("b:2")}
+	}}
+	queries, err := ApplyAll(multi, mock.Object("a:1"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []korrel8r.Query{s.NewQuery("b:1"), s.NewQuery("b:2")}, queries)
+
+	single := mock.NewRule("ab", "a", "b", func(korrel8r.Object, *korrel8r.Constraint) (korrel8r.Query, error) {
+		return s.NewQuery("b:1"), nil
+	})
+	queries, err = ApplyAll(single, mock.Object("a:1"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []korrel8r.Query{s.NewQuery("b:1")}, queries)
+
+	failed := mock.NewRule("ab", "a", "b", func(korrel8r.Object, *korrel8r.Constraint) (korrel8r.Query, error) {
+		return nil, korrel8r.ErrWrongGoal
+	})
+	_, err = ApplyAll(failed, mock.Object("a:1"), nil)
+	assert.ErrorIs(t, err, korrel8r.ErrWrongGoal)
+}
@@ -0,0 +1,190 @@
+package engine
+
+import (
+	"github.com/korrel8r/korrel8r/pkg/graph"
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+	"golang.org/x/exp/slices"
+)
+
+// Change describes how a class or rule differs between two correlations.
+type Change string
+
+const (
+	Unchanged Change = "unchanged"
+	Added     Change = "added"   // Only in the after correlation.
+	Removed   Change = "removed" // Only in the before correlation.
+	Changed   Change = "changed" // In both, with different results.
+)
+
+// Diff compares two correlations, for example before and after a fix.
+type Diff struct {
+	Nodes []NodeDiff `json:"nodes"` // Sorted by class name.
+	Lines []LineDiff `json:"lines"` // Sorted by rule name.
+	// Graph is the union of both correlation graphs.
+	// Nodes and lines hold the results of the after correlation, or the before correlation if they were removed.
+	Graph *graph.Graph `json:"-"`
+}
+
+// NodeDiff compares the objects of a class.
+// Objects are matched by korrel8r.ObjectID.
+type NodeDiff struct {
+	Class   string            `json:"class"`
+	Change  Change            `json:"change"`
+	Before  int               `json:"before"` // Number of objects before.
+	After   int               `json:"after"`  // Number of objects after.
+	Added   []korrel8r.Object `json:"added,omitempty"`
+	Removed []korrel8r.Object `json:"removed,omitempty"`
+}
+
+// LineDiff compares the query results of a rule.
+type LineDiff struct {
+	Rule   string `json:"rule"` // See korrel8r.RuleName
+	Change Change `json:"change"`
+	Before int    `json:"before"` // Total query results before.
+	After  int    `json:"after"`  // Total query results after.
+}
+
+// NewDiff compares the before and after correlations.
+func NewDiff(before, after *Correlation) *Diff {
+	d := &Diff{Nodes: []NodeDiff{}, Lines: []LineDiff{}}
+
+	// Lines, keyed by rule name.
+	beforeLines, afterLines := map[string]*graph.Line{}, map[string]*graph.Line{}
+	for _, l := range before.Graph.AllLines() {
+		beforeLines[korrel8r.RuleName(l.Rule)] = l
+	}
+	var lines []*graph.Line // Lines for the union graph.
+	for _, l := range after.Graph.AllLines() {
+		name := korrel8r.RuleName(l.Rule)
+		afterLines[name] = l
+		lines = append(lines, l)
+		ld := LineDiff{Rule: name, Change: Added, After: l.QueryCounts.Total()}
+		if bl, ok := beforeLines[name]; ok {
+			ld.Before = bl.QueryCounts.Total()
+			ld.Change = changed(ld.Before != ld.After)
+		}
+		d.Lines = append(d.Lines, ld)
+	}
+	for _, l := range before.Graph.AllLines() {
+		if name := korrel8r.RuleName(l.Rule); afterLines[name] == nil {
+			lines = append(lines, l)
+			d.Lines = append(d.Lines, LineDiff{Rule: name, Change: Removed, Before: l.QueryCounts.Total()})
+		}
+	}
+	slices.SortFunc(d.Lines, func(a, b LineDiff) bool { return a.Rule < b.Rule })
+
+	// Nodes, keyed by class name.
+	beforeNodes, afterNodes := map[string]*graph.Node{}, map[string]*graph.Node{}
+	for _, n := range before.Graph.AllNodes() {
+		beforeNodes[korrel8r.ClassName(n.Class)] = n
+	}
+	var nodes []*graph.Node // Nodes for the union graph.
+	for _, n := range after.Graph.AllNodes() {
+		name := korrel8r.ClassName(n.Class)
+		afterNodes[name] = n
+		nodes = append(nodes, n)
+		nd := NodeDiff{Class: name, Change: Added, After: len(n.Result.List()), Added: n.Result.List()}
+		if bn, ok := beforeNodes[name]; ok {
+			nd.Before = len(bn.Result.List())
+			nd.Added, nd.Removed = diffObjects(n.Class, bn.Result.List(), n.Result.List())
+			nd.Change = changed(nd.Before != nd.After || len(nd.Added) > 0 || len(nd.Removed) > 0)
+		}
+		d.Nodes = append(d.Nodes, nd)
+	}
+	for _, n := range before.Graph.AllNodes() {
+		if name := korrel8r.ClassName(n.Class); afterNodes[name] == nil {
+			nodes = append(nodes, n)
+			d.Nodes = append(d.Nodes, NodeDiff{Class: name, Change: Removed, Before: len(n.Result.List()), Removed: n.Result.List()})
+		}
+	}
+	slices.SortFunc(d.Nodes, func(a, b NodeDiff) bool { return a.Class < b.Class })
+
+	d.Graph = unionGraph(lines, nodes)
+	return d
+}
+
+// Node returns the NodeDiff for a class, or nil.
+func (d *Diff) Node(class korrel8r.Class) *NodeDiff {
+	name := korrel8r.ClassName(class)
+	if i := slices.IndexFunc(d.Nodes, func(nd NodeDiff) bool { return nd.Class == name }); i >= 0 {
+		return &d.Nodes[i]
+	}
+	return nil
+}
+
+// Line returns the LineDiff for a rule, or nil.
+func (d *Diff) Line(rule korrel8r.Rule) *LineDiff {
+	name := korrel8r.RuleName(rule)
+	if i := slices.IndexFunc(d.Lines, func(ld LineDiff) bool { return ld.Rule == name }); i >= 0 {
+		return &d.Lines[i]
+	}
+	return nil
+}
+
+// Empty is true if there are no differences.
+func (d *Diff) Empty() bool {
+	for _, nd := range d.Nodes {
+		if nd.Change != Unchanged {
+			return false
+		}
+	}
+	for _, ld := range d.Lines {
+		if ld.Change != Unchanged {
+			return false
+		}
+	}
+	return true
+}
+
+func changed(c bool) Change {
+	if c {
+		return Changed
+	}
+	return Unchanged
+}
+
+// diffObjects returns objects in after but not before, and in before but not after.
+func diffObjects(class korrel8r.Class, before, after []korrel8r.Object) (added, removed []korrel8r.Object) {
+	key := func(o korrel8r.Object) any { return korrel8r.ObjectID(class, o) }
+	beforeKeys, afterKeys := map[any]bool{}, map[any]bool{}
+	for _, o := range before {
+		beforeKeys[key(o)] = true
+	}
+	for _, o := range after {
+		k := key(o)
+		afterKeys[k] = true
+		if !beforeKeys[k] {
+			added = append(added, o)
+		}
+	}
+	for _, o := range before {
+		if !afterKeys[key(o)] {
+			removed = append(removed, o)
+		}
+	}
+	return added, removed
+}
+
+// unionGraph creates a graph with new graph.Data containing copies of lines and nodes from other graphs.
+func unionGraph(lines []*graph.Line, nodes []*graph.Node) *graph.Graph {
+	var rules []korrel8r.Rule
+	for _, l := range lines {
+		rules = append(rules, l.Rule)
+	}
+	data := graph.NewData(rules...)
+	g := data.EmptyGraph()
+	for i, l := range lines {
+		nl := data.Lines[i]
+		nl.QueryCounts, nl.Incomplete, nl.Truncated = l.QueryCounts, l.Incomplete, l.Truncated
+		g.SetLine(nl)
+	}
+	for _, n := range nodes {
+		nn := data.NodeFor(n.Class)
+		nn.Result.Append(n.Result.List()...)
+		nn.QueryCounts, nn.Incomplete, nn.Truncated = n.QueryCounts, n.Incomplete, n.Truncated
+		if g.Node(nn.ID()) == nil {
+			g.AddNode(nn)
+		}
+	}
+	return g
+}
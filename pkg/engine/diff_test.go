@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/korrel8r/korrel8r/internal/pkg/test/mock"
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDiff(t *testing.T) {
+	e, _ := correlateEngine()
+	before, err := e.Correlate(context.Background(), Request{Start: mock.Class("a"), Objects: mock.Objects("a:1", "a:2"), Goal: mock.Class("c")})
+	require.NoError(t, err)
+	after, err := e.Correlate(context.Background(), Request{Start: mock.Class("a"), Objects: mock.Objects("a:1", "a:3"), Goal: mock.Class("d")})
+	require.NoError(t, err)
+
+	d := NewDiff(before, after)
+	assert.False(t, d.Empty())
+	require.Len(t, d.Nodes, 4)
+	assert.Equal(t, []NodeDiff{
+		{Class: "/a", Change: Changed, Before: 2, After: 2, Added: mock.Objects("a:3"), Removed: mock.Objects("a:2")},
+		{Class: "/b", Change: Changed, Before: 2, After: 2, Added: mock.Objects("b:3"), Removed: mock.Objects("b:2")},
+		{Class: "/c", Change: Changed, Before: 2, After: 2, Added: mock.Objects("c:3"), Removed: mock.Objects("c:2")},
+	}, d.Nodes[:3])
+	nd := d.Nodes[3]
+	assert.Equal(t, NodeDiff{Class: "/d", Change: Added, Before: 0, After: 2}, NodeDiff{Class: nd.Class, Change: nd.Change, Before: nd.Before, After: nd.After})
+	assert.ElementsMatch(t, mock.Objects("d:1", "d:3"), nd.Added)
+	assert.Equal(t, []LineDiff{
+		{Rule: "ab [/a]->[/b]", Change: Unchanged, Before: 2, After: 2},
+		{Rule: "bc [/b]->[/c]", Change: Unchanged, Before: 2, After: 2},
+		{Rule: "cd [/c]->[/d]", Change: Added, Before: 0, After: 2},
+	}, d.Lines)
+	assert.Len(t, d.Graph.AllNodes(), 4)
+	assert.Len(t, d.Graph.AllLines(), 3)
+	assert.Equal(t, Added, d.Node(mock.Class("d")).Change)
+
+	// Reversed
+	d = NewDiff(after, before)
+	assert.Equal(t, Removed, d.Node(mock.Class("d")).Change)
+	assert.ElementsMatch(t, mock.Objects("d:1", "d:3"), d.Node(mock.Class("d")).Removed)
+	var cd korrel8r.Rule
+	for _, l := range d.Graph.AllLines() {
+		if l.Rule.String() == "cd" {
+			cd = l.Rule
+		}
+	}
+	require.NotNil(t, cd)
+	assert.Equal(t, Removed, d.Line(cd).Change)
+
+	assert.True(t, NewDiff(before, before).Empty())
+}
@@ -5,23 +5,28 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/korrel8r/korrel8r/pkg/cache"
 	"github.com/korrel8r/korrel8r/pkg/graph"
 	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+	"go.uber.org/multierr"
 	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 )
 
 // Engine combines a set of domains and a set of rules, so it can perform correlation.
 type Engine struct {
-	stores        map[string]korrel8r.Store
+	stores        map[string][]*storeEntry // Stores by domain name, in the order they were added.
 	domains       map[string]korrel8r.Domain
 	rules         []korrel8r.Rule
 	templateFuncs map[string]any
+	stats         *Stats // Rule statistics, may be nil.
 }
 
 func New() *Engine {
 	return &Engine{
-		stores:        map[string]korrel8r.Store{},
+		stores:        map[string][]*storeEntry{},
 		domains:       map[string]korrel8r.Domain{},
 		templateFuncs: map[string]any{},
 	}
@@ -39,13 +44,109 @@ func (e *Engine) DomainErr(name string) (korrel8r.Domain, error) {
 // Domains returns a list of known domains.
 func (e *Engine) Domains() (domains []korrel8r.Domain) { return maps.Values(e.domains) }
 
-// Store returns the default store for domain, or nil if not found.
-func (e *Engine) Store(name string) korrel8r.Store { return e.stores[name] }
-func (e *Engine) StoreErr(name string) (korrel8r.Store, error) {
-	if s := e.Store(name); s != nil {
+// Store returns a store by ID, or nil if not found.
+//
+// The ID is a domain name or 'domain/name', see StoreID.
+// A domain name returns the first store added for the domain.
+func (e *Engine) Store(id string) korrel8r.Store {
+	if se := e.storeEntries(id); len(se) > 0 {
+		return se[0].store
+	}
+	return nil
+}
+
+func (e *Engine) StoreErr(id string) (korrel8r.Store, error) {
+	if s := e.Store(id); s != nil {
 		return s, nil
 	}
-	return nil, fmt.Errorf("store not found: %v", name)
+	return nil, fmt.Errorf("store not found: %v", id)
+}
+
+// StoreIDs returns the IDs of stores for domain, in the order they were added.
+func (e *Engine) StoreIDs(domain string) (ids []string) {
+	for _, se := range e.stores[domain] {
+		ids = append(ids, se.id)
+	}
+	return ids
+}
+
+// storeEntries returns the stores selected by id.
+// A domain name selects all stores for the domain, 'domain/name' selects a single store.
+func (e *Engine) storeEntries(id string) []*storeEntry {
+	domain, name, named := strings.Cut(id, "/")
+	if !named {
+		return e.stores[domain]
+	}
+	if i := e.storeIndex(domain, name); i >= 0 {
+		return e.stores[domain][i : i+1]
+	}
+	return nil
+}
+
+// selectStores returns the stores for domain, or only the store named by selected[domain] if present.
+// The empty name selects only the unnamed store of the domain.
+func (e *Engine) selectStores(domain string, selected map[string]string) []*storeEntry {
+	if name, ok := selected[domain]; ok {
+		if i := e.storeIndex(domain, name); i >= 0 {
+			return e.stores[domain][i : i+1]
+		}
+		return nil
+	}
+	return e.stores[domain]
+}
+
+// SetStoreConcurrency limits the number of concurrent Get calls to the stores selected by id
+// made by Engine.Get and Follower. If n <= 0 there is no limit.
+// The id is a domain name for all stores of the domain, or 'domain/name' for a single store.
+// Must not be called while the engine is in use.
+func (e *Engine) SetStoreConcurrency(id string, n int) error {
+	return e.eachStore(id, func(se *storeEntry) {
+		se.limit = nil
+		if n > 0 {
+			se.limit = make(chan struct{}, n)
+		}
+	})
+}
+
+// SetStoreTimeout sets a timeout for each Get call to the stores selected by id
+// made by Engine.Get and Follower. If d <= 0 there is no timeout.
+// See SetStoreConcurrency for the meaning of id.
+// Must not be called while the engine is in use.
+func (e *Engine) SetStoreTimeout(id string, d time.Duration) error {
+	return e.eachStore(id, func(se *storeEntry) { se.timeout = d })
+}
+
+// SetStoreCache caches results from the stores selected by id, for use by Engine.Get and Follower.
+// See SetStoreConcurrency for the meaning of id, and cache.New for the meaning of ttl and size.
+// Must not be called while the engine is in use.
+func (e *Engine) SetStoreCache(id string, ttl time.Duration, size int) error {
+	return e.eachStore(id, func(se *storeEntry) { se.cache = cache.New(nil, ttl, size) })
+}
+
+// eachStore calls f for each store selected by id, then updates the store's wrappers.
+func (e *Engine) eachStore(id string, f func(*storeEntry)) error {
+	entries := e.storeEntries(id)
+	if len(entries) == 0 {
+		return fmt.Errorf("store not found: %v", id)
+	}
+	for _, se := range entries {
+		f(se)
+		se.update()
+	}
+	return nil
+}
+
+// CacheStats returns statistics for each store with a cache, by store ID.
+func (e *Engine) CacheStats() map[string]cache.Stats {
+	stats := map[string]cache.Stats{}
+	for _, entries := range e.stores {
+		for _, se := range entries {
+			if se.cache != nil {
+				stats[se.id] = se.cache.Stats()
+			}
+		}
+	}
+	return stats
 }
 
 // TemplateFuncser can be implemented by Domain or Store implementations to contribute
@@ -54,17 +155,59 @@ func (e *Engine) StoreErr(name string) (korrel8r.Store, error) {
 type TemplateFuncser interface{ TemplateFuncs() map[string]any }
 
 // AddDomain domain and corresponding store, store may be nil.
+// The store is unnamed, it replaces a previous unnamed store for the domain. See AddStore.
 func (e *Engine) AddDomain(d korrel8r.Domain, s korrel8r.Store) {
 	e.domains[d.String()] = d
-	if s != nil {
-		e.stores[d.String()] = s
-	}
-	// Stores and Domains implement TemplateFuncser if they provide template helper functions
-	// for use by rules.
-	for _, v := range []any{d, s} {
-		if tf, ok := v.(TemplateFuncser); ok {
-			maps.Copy(e.templateFuncs, tf.TemplateFuncs())
-		}
+	e.addTemplateFuncs(d)
+	if s == nil {
+		return
+	}
+	if i := e.storeIndex(d.String(), ""); i >= 0 {
+		se := e.stores[d.String()][i]
+		se.store = s
+		se.update()
+		e.addTemplateFuncs(s)
+	} else {
+		_ = e.AddStore(d.String(), "", s) // Can't fail, the domain exists and there is no unnamed store.
+	}
+}
+
+// AddStore adds a named store to a domain that was previously added by AddDomain.
+// A domain can have several stores, they must have different names.
+// The empty string is a valid name, see StoreID.
+func (e *Engine) AddStore(domain, name string, s korrel8r.Store) error {
+	if _, err := e.DomainErr(domain); err != nil {
+		return err
+	}
+	id := StoreID(domain, name)
+	if e.storeIndex(domain, name) >= 0 {
+		return fmt.Errorf("duplicate store: %v", id)
+	}
+	se := &storeEntry{id: id, name: name, store: s}
+	se.update()
+	e.stores[domain] = append(e.stores[domain], se)
+	e.addTemplateFuncs(s)
+	return nil
+}
+
+// storeIndex returns the index of the named store in e.stores[domain], or -1.
+func (e *Engine) storeIndex(domain, name string) int {
+	return slices.IndexFunc(e.stores[domain], func(se *storeEntry) bool { return se.name == name })
+}
+
+// StoreID returns the ID of a store: the domain name for an unnamed store, 'domain/name' otherwise.
+func StoreID(domain, name string) string {
+	if name == "" {
+		return domain
+	}
+	return domain + "/" + name
+}
+
+// Stores and Domains implement TemplateFuncser if they provide template helper functions
+// for use by rules.
+func (e *Engine) addTemplateFuncs(v any) {
+	if tf, ok := v.(TemplateFuncser); ok {
+		maps.Copy(e.templateFuncs, tf.TemplateFuncs())
 	}
 }
 
@@ -96,13 +239,43 @@ func (e *Engine) Graph() *graph.Graph { return graph.NewData(e.rules...).NewGrap
 // See text/template.Template.Funcs
 func (e *Engine) TemplateFuncs() map[string]any { return e.templateFuncs }
 
-// Get finds the store for the query.Class() and gets into result.
-func (e *Engine) Get(ctx context.Context, class korrel8r.Class, query korrel8r.Query, result korrel8r.Appender) error {
-	store, err := e.StoreErr(class.Domain().String())
-	if err != nil {
-		return err
+// Get gets query results from all the stores for class.Domain() into result.
+// Results from several stores are merged, errors are combined.
+func (e *Engine) Get(ctx context.Context, class korrel8r.Class, query korrel8r.Query, constraint *korrel8r.Constraint, result korrel8r.Appender) error {
+	entries := e.stores[class.Domain().String()]
+	if len(entries) == 0 {
+		return fmt.Errorf("store not found: %v", class.Domain())
+	}
+	var err error
+	for _, r := range getEach(ctx, entries, query, constraint) {
+		result.Append(r.objects...)
+		err = multierr.Append(err, r.err)
 	}
-	return store.Get(ctx, query, result)
+	return err
 }
 
-func (e *Engine) Follower(ctx context.Context) *Follower { return &Follower{Engine: e, Context: ctx} }
+// SetStats sets the rule statistics used by Cost, and updated by Correlate. May be nil.
+// Must not be called while the engine is in use.
+func (e *Engine) SetStats(s *Stats) { e.stats = s }
+
+// Stats returns the rule statistics, may be nil.
+func (e *Engine) Stats() *Stats { return e.stats }
+
+// Cost of following a rule, used to find cheapest paths.
+// The cost declared by the rule (see Coster) is divided by the estimated hit rate of the rule (see Stats),
+// so rules that rarely return results cost more.
+func (e *Engine) Cost(r korrel8r.Rule) float64 {
+	cost := 1.0
+	if c, ok := r.(Coster); ok && c.Cost() > 0 {
+		cost = c.Cost()
+	}
+	if e.stats != nil {
+		cost /= e.stats.Get(r).HitRate()
+	}
+	return cost
+}
+
+// Follower returns a Follower that applies constraint to every rule and store query, constraint may be nil.
+func (e *Engine) Follower(ctx context.Context, constraint *korrel8r.Constraint) *Follower {
+	return &Follower{Engine: e, Context: ctx, Constraint: constraint}
+}
@@ -2,9 +2,14 @@ package engine
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/korrel8r/korrel8r/internal/pkg/test/mock"
+	"github.com/korrel8r/korrel8r/pkg/cache"
 	"github.com/korrel8r/korrel8r/pkg/korrel8r"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -71,9 +76,9 @@ func TestFollower_Traverse(t *testing.T) {
 		}))
 	g := e.Graph()
 	g.NodeFor(mock.Class("a")).Result.Append(mock.Objects("a:0")...)
-	f := e.Follower(context.Background())
+	f := e.Follower(context.Background(), nil)
 	assert.NoError(t, g.Traverse(f.Traverse))
-	assert.NoError(t, f.Err)
+	assert.NoError(t, f.Err())
 	for _, x := range []struct {
 		class string
 		data  []korrel8r.Object
@@ -86,3 +91,143 @@ func TestFollower_Traverse(t *testing.T) {
 		assert.ElementsMatch(t, x.data, g.NodeFor(mock.Class(x.class)).Result.List())
 	}
 }
+
+func TestFollower_Constraint(t *testing.T) {
+	s := mock.Store{}
+	e := New()
+	e.AddDomain(mock.Domain(""), s)
+	limit := uint(1)
+	constraint := &korrel8r.Constraint{Limit: &limit}
+	var applied []*korrel8r.Constraint
+	e.AddRules(mock.NewRule("ab", "a", "b", func(_ korrel8r.Object, c *korrel8r.Constraint) (korrel8r.Query, error) {
+		applied = append(applied, c)
+		return s.NewQuery("b:1", "b:2"), nil
+	}))
+	g := e.Graph()
+	g.NodeFor(mock.Class("a")).Result.Append(mock.Objects("a:0")...)
+	f := e.Follower(context.Background(), constraint)
+	assert.NoError(t, g.Traverse(f.Traverse))
+	assert.Equal(t, []*korrel8r.Constraint{constraint}, applied)
+	assert.Equal(t, mock.Objects("b:1"), g.NodeFor(mock.Class("b")).Result.List())
+}
+
+// gateStore records the maximum number of concurrent calls to Get.
+// If entered is not nil, Get sends on entered and waits on release before returning.
+type gateStore struct {
+	mock.Store
+	entered, release chan struct{}
+	mu               sync.Mutex
+	active, peak     int
+}
+
+func newGateStore() *gateStore {
+	return &gateStore{Store: mock.Store{}, entered: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (s *gateStore) Get(ctx context.Context, q korrel8r.Query, c *korrel8r.Constraint, r korrel8r.Appender) error {
+	s.mu.Lock()
+	s.active++
+	if s.active > s.peak {
+		s.peak = s.active
+	}
+	s.mu.Unlock()
+	if s.entered != nil {
+		s.entered <- struct{}{}
+		<-s.release
+	}
+	s.mu.Lock()
+	s.active--
+	s.mu.Unlock()
+	return s.Store.Get(ctx, q, c, r)
+}
+
+func TestFollower_Concurrency(t *testing.T) {
+	for _, x := range []struct {
+		limit, want int
+	}{{0, 6}, {1, 1}, {3, 3}} {
+		t.Run(fmt.Sprintf("limit=%v", x.limit), func(t *testing.T) {
+			s := newGateStore()
+			e := New()
+			e.AddDomain(mock.Domain(""), s)
+			e.SetStoreConcurrency("", x.limit)
+			e.AddRules(mock.NewRule("ab", "a", "b", follow(s.Store, "b")))
+			g := e.Graph()
+			start := g.NodeFor(mock.Class("a"))
+			var want []korrel8r.Object
+			for i := 0; i < 6; i++ {
+				start.Result.Append(mock.Object(fmt.Sprintf("a:%v", i)))
+				want = append(want, mock.Object(fmt.Sprintf("b:%v", i)))
+			}
+			f := e.Follower(context.Background(), nil)
+			done := make(chan error)
+			go func() { done <- g.Traverse(f.Traverse) }()
+			// Wait for a full batch of concurrent calls before releasing them.
+			for i := 0; i < 6; i += x.want {
+				for j := 0; j < x.want; j++ {
+					<-s.entered
+				}
+				for j := 0; j < x.want; j++ {
+					s.release <- struct{}{}
+				}
+			}
+			assert.NoError(t, <-done)
+			assert.ElementsMatch(t, want, g.NodeFor(mock.Class("b")).Result.List())
+			assert.Equal(t, x.want, s.peak)
+		})
+	}
+}
+
+func TestEngine_StoreConcurrency_Unwrapped(t *testing.T) {
+	s := &gateStore{Store: mock.Store{}}
+	e := New()
+	e.AddDomain(mock.Domain(""), s)
+	e.SetStoreConcurrency("", 1)
+	// Store returns the original store so optional interfaces are visible.
+	assert.Same(t, s, e.Store(""))
+}
+
+// errStore fails every Get.
+type errStore struct{ mock.Store }
+
+func (errStore) Get(context.Context, korrel8r.Query, *korrel8r.Constraint, korrel8r.Appender) error {
+	return errors.New("broken")
+}
+
+func TestFollower_Failures(t *testing.T) {
+	s := mock.Store{}
+	e := New()
+	e.AddDomain(mock.Domain(""), s)
+	e.AddDomain(mock.Domain("bad"), errStore{})
+	e.AddRules(
+		mock.NewRule("ab", "a", "b", func(korrel8r.Object, *korrel8r.Constraint) (korrel8r.Query, error) {
+			return nil, errors.New("no match")
+		}),
+		mock.NewRule("ax", "a", "bad/x", func(korrel8r.Object, *korrel8r.Constraint) (korrel8r.Query, error) {
+			return mock.Query("bad/x:1"), nil
+		}))
+	g := e.Graph()
+	g.NodeFor(mock.Class("a")).Result.Append(mock.Objects("a:0")...)
+	f := e.Follower(context.Background(), nil)
+	assert.NoError(t, g.Traverse(f.Traverse))
+	assert.ElementsMatch(t, Failures{
+		{Kind: RuleFailed, Rule: "ab", Start: "/a", Goal: "/b", Err: errors.New("no match"), Msg: "no match"},
+		{Kind: StoreFailed, Rule: "ax", Start: "/a", Goal: "bad/x", Query: mock.Query("bad/x:1"), Store: "bad", Err: errors.New("broken"), Msg: "broken"},
+	}, f.Failures)
+	assert.EqualError(t, f.Err(), `store failed: ax [/a]->[bad/x] store bad query "bad/x:1": broken`)
+}
+
+func TestEngine_StoreCache(t *testing.T) {
+	s := &gateStore{Store: mock.Store{}}
+	e := New()
+	e.AddDomain(mock.Domain(""), s)
+	require.NoError(t, e.SetStoreCache("", time.Minute, 10))
+	assert.EqualError(t, e.SetStoreCache("nonesuch", time.Minute, 10), "store not found: nonesuch")
+	q := s.NewQuery("a:1")
+	for i := 0; i < 3; i++ {
+		r := korrel8r.NewListResult()
+		require.NoError(t, e.Get(context.Background(), mock.Class("a"), q, nil, r))
+		assert.Equal(t, mock.Objects("a:1"), r.List())
+	}
+	assert.Equal(t, cache.Stats{Hits: 2, Misses: 1, Entries: 1}, e.CacheStats()[""])
+	assert.Same(t, s, e.Store(""))
+}
@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+	"golang.org/x/exp/slices"
+)
+
+// FailureKind classifies a Failure.
+type FailureKind string
+
+const (
+	RuleFailed   FailureKind = "rule did not apply"
+	StoreFailed  FailureKind = "store failed"
+	StoreTimeout FailureKind = "store timed out"
+)
+
+// Failure describes a single failure to follow a rule.
+//
+// A RuleFailed failure usually means there is no correlation for a particular start object.
+// A StoreFailed failure means the correlation may be missing data because of a broken backend.
+// A StoreTimeout failure means the correlation may be missing data because of a slow backend.
+type Failure struct {
+	Kind  FailureKind    `json:"kind"`
+	Rule  string         `json:"rule"`            // Empty for a start query.
+	Start string         `json:"start"`           // Full name of start class.
+	Goal  string         `json:"goal"`            // Full name of goal class.
+	Query korrel8r.Query `json:"query,omitempty"` // Query generated by the rule, nil for RuleFailed.
+	Store string         `json:"store,omitempty"` // Store that failed, empty for RuleFailed.
+	Err   error          `json:"-"`
+	Msg   string         `json:"error"` // Err.Error(), for serialization.
+}
+
+func newFailure(kind FailureKind, rule korrel8r.Rule, query korrel8r.Query, store string, err error) Failure {
+	return Failure{
+		Kind:  kind,
+		Rule:  rule.String(),
+		Start: korrel8r.ClassName(rule.Start()),
+		Goal:  korrel8r.ClassName(rule.Goal()),
+		Query: query,
+		Store: store,
+		Err:   err,
+		Msg:   err.Error(),
+	}
+}
+
+// newStartFailure returns a failure of a start query for class.
+func newStartFailure(kind FailureKind, class korrel8r.Class, query korrel8r.Query, store string, err error) Failure {
+	name := korrel8r.ClassName(class)
+	return Failure{Kind: kind, Start: name, Goal: name, Query: query, Store: store, Err: err, Msg: err.Error()}
+}
+
+func (f Failure) Error() string {
+	s := fmt.Sprintf("%v: %v [%v]->[%v]", f.Kind, f.Rule, f.Start, f.Goal)
+	if f.Rule == "" {
+		s = fmt.Sprintf("%v: start [%v]", f.Kind, f.Start)
+	}
+	if f.Store != "" {
+		s = fmt.Sprintf("%v store %v", s, f.Store)
+	}
+	if f.Query != nil {
+		s = fmt.Sprintf("%v query %v", s, korrel8r.JSONString(f.Query))
+	}
+	return fmt.Sprintf("%v: %v", s, f.Msg)
+}
+
+func (f Failure) Unwrap() error { return f.Err }
+
+// Failures is a list of failures, it implements error if not empty.
+type Failures []Failure
+
+func (fs Failures) Error() string {
+	var b strings.Builder
+	for i, f := range fs {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(f.Error())
+	}
+	return b.String()
+}
+
+// Kind returns the failures of the given kinds.
+func (fs Failures) Kind(kinds ...FailureKind) (found Failures) {
+	for _, f := range fs {
+		if slices.Contains(kinds, f.Kind) {
+			found = append(found, f)
+		}
+	}
+	return found
+}
+
+// Err returns the failures as an error, or nil if there are none.
+func (fs Failures) Err() error {
+	if len(fs) == 0 {
+		return nil
+	}
+	return fs
+}
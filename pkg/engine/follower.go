@@ -2,56 +2,306 @@ package engine
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 
+	"github.com/go-logr/logr"
 	"github.com/korrel8r/korrel8r/internal/pkg/logging"
 	"github.com/korrel8r/korrel8r/pkg/graph"
 	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+	"github.com/korrel8r/korrel8r/pkg/unique"
 )
 
 var log = logging.Log()
 
 // Follower provide a Traverse() method to follow rules and collect results in a graph.
+//
+// Traverse is safe for concurrent use, as required by graph.Graph.Traverse.
 type Follower struct {
 	Engine  *Engine
 	Context context.Context
-	Err     error // Collect errors using multierror
+	// Constraint is passed to Rule.Apply and Store.Get, may be nil.
+	// Rules that implement Constrainer narrow the constraint for their results, and for rules applied to those results.
+	Constraint *korrel8r.Constraint
+	// Stores selects a single store by name for some domains, map[domain]name.
+	// Queries for other domains are sent to all stores for the domain.
+	Stores   map[string]string
+	Limits   Limits   // Limits on the results and queries of a traversal.
+	Failures Failures // Failures collected during traversal.
+
+	mu          sync.Mutex         // Guards Failures and the Result and QueryCounts of graph nodes and lines.
+	failed      unique.Set[string] // Failures already recorded, a line may be traversed more than once.
+	applied     map[int64]int      // Number of start objects each line's rule was applied to, by line ID.
+	queries     int                // Number of queries sent.
+	lineQueries map[int64]int      // Number of queries sent for each line, by line ID.
+	sent        unique.Set[string] // Queries sent, by goal node, query and constraint, see reserve.
+	// Constraints for objects found with a narrower constraint than Constraint, by node ID and korrel8r.ObjectID.
+	constraints map[int64]map[any]*korrel8r.Constraint
+}
+
+// Constrainer can be implemented by a korrel8r.Rule to narrow the constraint for queries generated from a start object.
+// Constraint returns the constraint for queries from start, usually constraint.Combine with a constraint derived from start.
+// The rule is applied with the returned constraint, which is also used when following rules from the results of those queries.
+// If the returned constraint is empty (see korrel8r.Constraint.Empty) the queries are skipped, nothing can match.
+type Constrainer interface {
+	Constraint(start korrel8r.Object, constraint *korrel8r.Constraint) (*korrel8r.Constraint, error)
+}
+
+// Limits cap the fan-out of a correlation, so a start object with many related objects
+// does not lead to an explosion of queries and results. Zero values mean no limit.
+//
+// Nodes and lines that reach a limit are marked Truncated, the data is there but was not collected.
+type Limits struct {
+	MaxObjects     int `json:"maxObjects,omitempty"`     // Max objects in each node.
+	MaxLineQueries int `json:"maxLineQueries,omitempty"` // Max queries generated by each line.
+	MaxQueries     int `json:"maxQueries,omitempty"`     // Max queries for the whole correlation.
+}
+
+// appendResults appends objects to node with their origin, up to MaxObjects.
+// If objects are dropped the node is marked Truncated.
+func (lim Limits) appendResults(node *graph.Node, origin graph.Origin, objects []korrel8r.Object) {
+	for _, o := range objects {
+		if lim.MaxObjects > 0 && len(node.Result.List()) >= lim.MaxObjects {
+			node.Truncated = true
+			return
+		}
+		node.Result.Append(o)
+		node.AddOrigin(o, origin)
+	}
+}
+
+// Err returns store failures and timeouts as an error, or nil if there were none.
+// Rules that did not apply are not considered errors, see Failures.
+func (v *Follower) Err() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.Failures.Kind(StoreFailed, StoreTimeout).Err()
+}
+
+func (v *Follower) fail(f Failure) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.failed == nil {
+		v.failed = unique.Set[string]{}
+	}
+	if v.failed.Add(f.Error()) {
+		v.Failures = append(v.Failures, f)
+	}
 }
 
+// Traverse applies the rule of line l to each object in the start node,
+// and gets the resulting queries concurrently from the goal stores.
+// Results from all stores are merged in the goal node, QueryCounts record the count from each store.
+// Line.Parents and Node.Origins record the provenance of the results, see graph.Node.Provenance.
+//
+// If a line is traversed again, the rule is applied only to start objects added since the last traversal.
 func (v *Follower) Traverse(l *graph.Line) {
 	rule := graph.RuleFor(l)
 	log := log.WithValues("rule", korrel8r.RuleName(rule))
 	startNode, goalNode := l.From().(*graph.Node), l.To().(*graph.Node)
 
+	v.mu.Lock()
+	if v.applied == nil {
+		v.applied = map[int64]int{}
+	}
 	starters := startNode.Result.List()
+	starters = starters[v.applied[l.ID()]:]
+	v.applied[l.ID()] += len(starters)
+	v.mu.Unlock()
 	if len(starters) == 0 {
 		return
 	}
-	store := v.Engine.Store(rule.Goal().Domain().String())
-	if store == nil {
+	stores := v.Engine.selectStores(rule.Goal().Domain().String(), v.Stores)
+	if len(stores) == 0 {
 		log.V(2).Info("no store for goal")
 		// Don't return, we want to generate final queries even if there is no store.
 	}
+	var wg sync.WaitGroup
 	for _, s := range starters {
-		query, err := rule.Apply(s, nil)
+		constraint := v.constraintFor(startNode, s)
+		if cr, ok := rule.(Constrainer); ok {
+			var err error
+			if constraint, err = cr.Constraint(s, constraint); err != nil {
+				log.V(3).Error(err, "constraint did not apply")
+				v.fail(newFailure(RuleFailed, rule, nil, "", err))
+				continue
+			}
+			if constraint.Empty() {
+				log.V(3).Info("skip rule, constraint time window is empty")
+				continue
+			}
+		}
+		queries, err := ApplyAll(rule, s, constraint)
+		if errors.Is(err, korrel8r.ErrWrongGoal) {
+			log.V(3).Info("start object leads to another goal")
+			continue // Not a failure, another rule from the same wildcard applies.
+		}
 		if err != nil {
 			log.V(3).Error(err, "did not apply")
+			v.fail(newFailure(RuleFailed, rule, nil, "", err))
 			continue
 		}
-		log := log.WithValues("query", logging.JSON(query))
-		if _, ok := goalNode.QueryCounts.Get(query); ok {
-			log.V(3).Info("skip duplicate query")
-			continue
+		for _, query := range queries {
+			query := query
+			log := log.WithValues("query", logging.JSON(query))
+			v.mu.Lock()
+			l.AddParent(query, s)
+			v.mu.Unlock()
+			if !v.reserve(l, goalNode, query, constraint) {
+				log.V(3).Info("skip duplicate query, or query limit reached")
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v.get(l, stores, query, constraint, log)
+			}()
 		}
-		result := korrel8r.NewCountResult(goalNode.Result)
-		if store != nil {
-			if err := store.Get(v.Context, query, result); err != nil {
-				// FIXME should report this error, but causing a test failure, investigate.
-				// v.Err = multierr.Append(v.Err, err)
-				log.Error(err, "store get error")
+	}
+	wg.Wait()
+}
+
+// MultiApplier can be implemented by a korrel8r.Rule that can generate several queries from one start object.
+// The Follower calls ApplyAll instead of Rule.Apply, and gets each query, skipping duplicates.
+// ApplyAll returns errors in the same way as Rule.Apply, it returns at least one query if there is no error.
+type MultiApplier interface {
+	ApplyAll(start korrel8r.Object, constraint *korrel8r.Constraint) ([]korrel8r.Query, error)
+}
+
+// ApplyAll returns all the queries generated by applying rule to start.
+// It calls MultiApplier.ApplyAll if rule implements it, Rule.Apply otherwise.
+func ApplyAll(rule korrel8r.Rule, start korrel8r.Object, constraint *korrel8r.Constraint) ([]korrel8r.Query, error) {
+	if ma, ok := rule.(MultiApplier); ok {
+		return ma.ApplyAll(start, constraint)
+	}
+	q, err := rule.Apply(start, constraint)
+	if err != nil {
+		return nil, err
+	}
+	return []korrel8r.Query{q}, nil
+}
+
+// get the results of query from stores, and add them to the goal node of line l.
+func (v *Follower) get(l *graph.Line, stores []*storeEntry, query korrel8r.Query, constraint *korrel8r.Constraint, log logr.Logger) {
+	rule, goalNode := graph.RuleFor(l), l.To().(*graph.Node)
+	results := getEach(v.Context, stores, query, constraint)
+	for _, r := range results {
+		if r.err != nil {
+			log.V(1).Error(r.err, "store get error", "store", r.id)
+			kind := StoreFailed
+			if errors.Is(r.err, context.DeadlineExceeded) {
+				kind = StoreTimeout
 			}
+			v.fail(newFailure(kind, rule, query, r.id, r.err))
+		}
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if _, ok := l.QueryCounts.Get(query); !ok {
+		l.QueryCounts.Put(query, 0)
+	}
+	for _, r := range results {
+		if r.err != nil { // Keep partial results, but mark them incomplete.
+			l.Incomplete = true
+			goalNode.Incomplete = true
+		}
+		v.Limits.appendResults(goalNode, graph.Origin{Line: l, Query: query}, r.objects)
+		v.setConstraint(goalNode, r.objects, constraint)
+		l.QueryCounts.Add(query, r.id, len(r.objects))
+		goalNode.QueryCounts.Add(query, r.id, len(r.objects))
+		log.V(3).Info("query results", "store", r.id, "count", len(r.objects))
+	}
+}
+
+// constraintFor returns the constraint for rules applied to start object o.
+// Must be called with v.mu unlocked.
+func (v *Follower) constraintFor(n *graph.Node, o korrel8r.Object) *korrel8r.Constraint {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if c, ok := v.constraints[n.ID()][korrel8r.ObjectID(n.Class, o)]; ok {
+		return c
+	}
+	return v.Constraint
+}
+
+// setConstraint records the constraint for objects in node n.
+// If an object was already found with another constraint, it gets the union of both, see union.
+// Must be called with v.mu locked.
+func (v *Follower) setConstraint(n *graph.Node, objects []korrel8r.Object, c *korrel8r.Constraint) {
+	if v.constraints == nil {
+		v.constraints = map[int64]map[any]*korrel8r.Constraint{}
+	}
+	if v.constraints[n.ID()] == nil {
+		v.constraints[n.ID()] = map[any]*korrel8r.Constraint{}
+	}
+	for _, o := range objects {
+		id := korrel8r.ObjectID(n.Class, o)
+		if old, ok := v.constraints[n.ID()][id]; ok {
+			c = union(old, c)
+		}
+		v.constraints[n.ID()][id] = c
+	}
+}
+
+// union returns a constraint that includes everything allowed by a or b.
+// The time window spans both windows, and the limit is the larger limit. A nil constraint places no restriction.
+func union(a, b *korrel8r.Constraint) *korrel8r.Constraint {
+	if a == nil || b == nil {
+		return nil
+	}
+	if a == b {
+		return a
+	}
+	u := &korrel8r.Constraint{}
+	if a.Limit != nil && b.Limit != nil {
+		u.Limit = a.Limit
+		if *b.Limit > *a.Limit {
+			u.Limit = b.Limit
+		}
+	}
+	if a.Start != nil && b.Start != nil {
+		u.Start = a.Start
+		if b.Start.Before(*a.Start) {
+			u.Start = b.Start
+		}
+	}
+	if a.End != nil && b.End != nil {
+		u.End = a.End
+		if b.End.After(*a.End) {
+			u.End = b.End
 		}
-		l.QueryCounts.Put(query, result.Count)
-		goalNode.QueryCounts.Put(query, result.Count)
-		log.V(3).Info("query results", "count", result.Count)
 	}
+	return u
+}
+
+// reserve records a query with a constraint from line l on node.
+// Returns false if the query was already sent with the same constraint, or if a query limit was reached.
+// The same query with a different constraint is sent again, it may return different objects.
+// If a limit was reached, the line and node are marked Truncated.
+func (v *Follower) reserve(l *graph.Line, node *graph.Node, query korrel8r.Query, constraint *korrel8r.Constraint) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	key := fmt.Sprintf("%v %v %v", node.ID(), korrel8r.JSONString(query), korrel8r.JSONString(constraint))
+	if v.sent.Has(key) {
+		return false
+	}
+	if v.lineQueries == nil {
+		v.lineQueries = map[int64]int{}
+	}
+	if (v.Limits.MaxQueries > 0 && v.queries >= v.Limits.MaxQueries) ||
+		(v.Limits.MaxLineQueries > 0 && v.lineQueries[l.ID()] >= v.Limits.MaxLineQueries) {
+		l.Truncated, node.Truncated = true, true
+		return false
+	}
+	if v.sent == nil {
+		v.sent = unique.Set[string]{}
+	}
+	v.sent.Add(key)
+	v.queries++
+	v.lineQueries[l.ID()]++
+	if _, ok := node.QueryCounts.Get(query); !ok {
+		node.QueryCounts.Put(query, 0)
+	}
+	return true
 }
@@ -0,0 +1,164 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/korrel8r/korrel8r/pkg/graph"
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+	"github.com/korrel8r/korrel8r/pkg/unique"
+	"golang.org/x/exp/slices"
+	"gonum.org/v1/gonum/graph/topo"
+)
+
+// Templater can be implemented by a korrel8r.Rule that generates queries from a template.
+// Template returns the template text, Lint reports rules with the same start, goal and template as duplicates.
+type Templater interface{ Template() string }
+
+// ProblemKind classifies a Problem.
+type ProblemKind string
+
+const (
+	NoStore     ProblemKind = "no store"    // The goal domain of a rule has no store, only checked if the engine has stores.
+	Unreachable ProblemKind = "unreachable" // A class cannot be reached from any class in the lint domains.
+	Unused      ProblemKind = "unused"      // A rule has never generated a query, according to the engine Stats.
+	Duplicate   ProblemKind = "duplicate"   // Rules with the same start, goal and template.
+	Cycle       ProblemKind = "cycle"       // Classes that can be reached from themselves, a warning.
+)
+
+// Warning is true for kinds of problem that are reported but are not errors.
+// Cycles are expected in rule sets that correlate in both directions.
+func (k ProblemKind) Warning() bool { return k == Cycle }
+
+// Problem describes a problem with the rules found by Engine.Lint.
+type Problem struct {
+	Kind  ProblemKind `json:"kind"`
+	Rule  string      `json:"rule,omitempty"`  // Rule with the problem, see korrel8r.RuleName.
+	Class string      `json:"class,omitempty"` // Full name of the class with the problem.
+	Msg   string      `json:"message"`
+}
+
+func (p Problem) String() string {
+	var s []string
+	for _, v := range []string{string(p.Kind), p.Rule, p.Class, p.Msg} {
+		if v != "" {
+			s = append(s, v)
+		}
+	}
+	return strings.Join(s, ": ")
+}
+
+// Problems is a list of Problem.
+type Problems []Problem
+
+// Err returns an error summarizing the problems, or nil if there are none.
+// Warnings are not counted, see ProblemKind.Warning.
+func (ps Problems) Err() error {
+	n := 0
+	for _, p := range ps {
+		if !p.Kind.Warning() {
+			n++
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+	return fmt.Errorf("found %v problems with rules", n)
+}
+
+// Sort problems by kind, rule, class and message.
+func (ps Problems) Sort() {
+	slices.SortFunc(ps, func(a, b Problem) bool {
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		if a.Class != b.Class {
+			return a.Class < b.Class
+		}
+		return a.Msg < b.Msg
+	})
+}
+
+// Lint checks the engine's rules for problems, see ProblemKind.
+//
+// Every class used by a rule should be reachable from one of the classes in the from domains, for example "alert".
+// If from is empty the reachability check is skipped.
+// Unused rules are only reported if the engine has Stats.
+// Missing stores are only reported if the engine has at least one store.
+func (e *Engine) Lint(from ...string) Problems {
+	var ps Problems
+	g := e.Graph()
+	hasStores := false
+	for _, stores := range e.stores {
+		hasStores = hasStores || len(stores) > 0
+	}
+
+	duplicates := map[string][]string{} // Rule names by start, goal and template.
+	for _, r := range e.rules {
+		name := korrel8r.RuleName(r)
+		if hasStores && len(e.stores[r.Goal().Domain().String()]) == 0 {
+			ps = append(ps, Problem{Kind: NoStore, Rule: name, Msg: fmt.Sprintf("no store for goal domain %v", r.Goal().Domain())})
+		}
+		if e.stats != nil && e.stats.Get(r).Queries == 0 {
+			ps = append(ps, Problem{Kind: Unused, Rule: name, Msg: "rule has never generated a query"})
+		}
+		if t, ok := r.(Templater); ok {
+			key := strings.Join([]string{korrel8r.ClassName(r.Start()), korrel8r.ClassName(r.Goal()), t.Template()}, "\n")
+			duplicates[key] = append(duplicates[key], name)
+		}
+	}
+	for _, names := range duplicates {
+		for i := 1; i < len(names); i++ {
+			ps = append(ps, Problem{Kind: Duplicate, Rule: names[i], Msg: fmt.Sprintf("same start, goal and template as %v", names[0])})
+		}
+	}
+
+	if len(from) > 0 {
+		reached := reachable(g, from)
+		g.EachNode(func(n *graph.Node) {
+			if !reached.Has(n.ID()) {
+				ps = append(ps, Problem{Kind: Unreachable, Class: korrel8r.ClassName(n.Class), Msg: fmt.Sprintf("not reachable from %v", strings.Join(from, ", "))})
+			}
+		})
+	}
+
+	for _, scc := range topo.TarjanSCC(g) {
+		if len(scc) == 1 && !g.HasEdgeFromTo(scc[0].ID(), scc[0].ID()) {
+			continue
+		}
+		var names []string
+		for _, n := range scc {
+			names = append(names, n.(*graph.Node).String())
+		}
+		slices.Sort(names)
+		ps = append(ps, Problem{Kind: Cycle, Class: names[0], Msg: fmt.Sprintf("cycle between classes: %v", strings.Join(names, ", "))})
+	}
+
+	ps.Sort()
+	return ps
+}
+
+// reachable returns the IDs of nodes in g that can be reached from a class in one of the domains.
+func reachable(g *graph.Graph, domains []string) unique.Set[int64] {
+	reached := unique.Set[int64]{}
+	var frontier []*graph.Node
+	g.EachNode(func(n *graph.Node) {
+		if slices.Contains(domains, n.Class.Domain().String()) && reached.Add(n.ID()) {
+			frontier = append(frontier, n)
+		}
+	})
+	for len(frontier) > 0 {
+		n := frontier[0]
+		frontier = frontier[1:]
+		to := g.From(n.ID())
+		for to.Next() {
+			if next := to.Node().(*graph.Node); reached.Add(next.ID()) {
+				frontier = append(frontier, next)
+			}
+		}
+	}
+	return reached
+}
@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/korrel8r/korrel8r/internal/pkg/test/mock"
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+	"github.com/stretchr/testify/assert"
+)
+
+// templateRule is a mock rule with a template.
+type templateRule struct {
+	mock.Rule
+	template string
+}
+
+func (r templateRule) Template() string { return r.template }
+
+func TestEngine_Lint(t *testing.T) {
+	e := New()
+	e.AddDomain(mock.Domain("x"), mock.Store{})
+	e.AddDomain(mock.Domain("y"), nil)
+	e.AddRules(
+		mock.NewRule("ab", "x/a", "x/b", nil),
+		templateRule{mock.NewRule("bc", "x/b", "x/c", nil), "T"},
+		templateRule{mock.NewRule("bc2", "x/b", "x/c", nil), "T"},
+		templateRule{mock.NewRule("bc3", "x/b", "x/c", nil), "other"},
+		mock.NewRule("cb", "x/c", "x/b", nil),
+		mock.NewRule("dy", "x/d", "y/e", nil),
+	)
+	var got []string
+	for _, p := range e.Lint("x") {
+		got = append(got, p.String())
+	}
+	assert.Equal(t, []string{
+		"cycle: x/b: cycle between classes: x/b, x/c",
+		"duplicate: bc2 [x/b]->[x/c]: same start, goal and template as bc [x/b]->[x/c]",
+		"no store: dy [x/d]->[y/e]: no store for goal domain y",
+	}, got)
+
+	assert.EqualError(t, e.Lint("x").Err(), "found 2 problems with rules", "cycles are warnings")
+
+	// No store check without stores.
+	e2 := New()
+	e2.AddDomain(mock.Domain("x"), nil)
+	e2.AddRules(mock.NewRule("ab", "x/a", "x/b", nil))
+	assert.Empty(t, e2.Lint("x"))
+
+	// Nothing is reachable from an unknown domain.
+	got = nil
+	for _, p := range e.Lint("nosuchdomain") {
+		if p.Kind == Unreachable {
+			got = append(got, p.Class)
+		}
+	}
+	assert.ElementsMatch(t, []string{"x/a", "x/b", "x/c", "x/d", "y/e"}, got)
+
+	// Unused rules, with statistics.
+	e.SetStats(NewStats())
+	e.stats.rules[korrel8r.RuleName(e.rules[0])] = RuleStats{Queries: 1}
+	got = nil
+	for _, p := range e.Lint() {
+		if p.Kind == Unused {
+			got = append(got, p.Rule[:strings.Index(p.Rule, " ")])
+		}
+	}
+	assert.Equal(t, []string{"bc", "bc2", "bc3", "cb", "dy"}, got)
+}
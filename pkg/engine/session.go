@@ -0,0 +1,353 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/korrel8r/korrel8r/pkg/graph"
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+	"golang.org/x/exp/slices"
+)
+
+// session is the saved form of a Correlation, see Correlation.Save and Engine.LoadCorrelation.
+// Classes are full class names, queries and objects use the JSON form of their domain.
+type session struct {
+	Start    string           `json:"start"`
+	Goal     string           `json:"goal,omitempty"`
+	Reverse  bool             `json:"reverse,omitempty"`
+	Options  sessionOptions   `json:"options"`
+	Nodes    []sessionNode    `json:"nodes"`
+	Lines    []sessionLine    `json:"lines"`
+	Failures []sessionFailure `json:"failures,omitempty"`
+}
+
+// sessionOptions are the options of the correlation Request.
+type sessionOptions struct {
+	Depth         int                  `json:"depth,omitempty"`
+	ShortestPaths bool                 `json:"shortestPaths,omitempty"`
+	KShortest     int                  `json:"kShortest,omitempty"`
+	MaxPathLength int                  `json:"maxPathLength,omitempty"`
+	MaxPaths      int                  `json:"maxPaths,omitempty"`
+	RulesOnly     bool                 `json:"rulesOnly,omitempty"`
+	Constraint    *korrel8r.Constraint `json:"constraint,omitempty"`
+	Timeout       time.Duration        `json:"timeout,omitempty"`
+	Limits        Limits               `json:"limits"`
+	Stores        map[string]string    `json:"stores,omitempty"`
+}
+
+type sessionNode struct {
+	Class      string            `json:"class"`
+	Objects    []json.RawMessage `json:"objects,omitempty"`
+	Origins    [][]sessionOrigin `json:"origins,omitempty"` // Origins[i] are the origins of Objects[i].
+	Queries    []sessionQuery    `json:"queries,omitempty"`
+	Incomplete bool              `json:"incomplete,omitempty"`
+	Truncated  bool              `json:"truncated,omitempty"`
+}
+
+type sessionLine struct {
+	Rule       string          `json:"rule"`
+	Start      string          `json:"start"`
+	Goal       string          `json:"goal"`
+	Queries    []sessionQuery  `json:"queries,omitempty"`
+	Parents    []sessionParent `json:"parents,omitempty"`
+	Incomplete bool            `json:"incomplete,omitempty"`
+	Truncated  bool            `json:"truncated,omitempty"`
+}
+
+// sessionOrigin is a graph.Origin, Line is an index in session.Lines or nil for a start query.
+type sessionOrigin struct {
+	Line  *int            `json:"line,omitempty"`
+	Query json.RawMessage `json:"query"`
+}
+
+// sessionParent lists the start objects that generated a query, see graph.Line.Parents.
+// Objects are indices in the Objects of the line's start node.
+type sessionParent struct {
+	Query   json.RawMessage `json:"query"`
+	Objects []int           `json:"objects"`
+}
+
+type sessionQuery struct {
+	Query  json.RawMessage `json:"query"`
+	Count  int             `json:"count"`
+	Stores map[string]int  `json:"stores,omitempty"`
+}
+
+// sessionFailure is a Failure with an undecoded query.
+type sessionFailure struct {
+	Failure
+	Query json.RawMessage `json:"query,omitempty"`
+}
+
+// Save writes the correlation to w as JSON, including result objects, query counts and request options.
+// The correlation can be reloaded without access to stores, see Engine.LoadCorrelation.
+func (c *Correlation) Save(w io.Writer) error {
+	r := c.request
+	s := session{
+		Start:   korrel8r.ClassName(c.Start),
+		Reverse: c.Reverse,
+		Options: sessionOptions{
+			Depth: r.Depth, ShortestPaths: r.ShortestPaths, KShortest: r.KShortest, MaxPathLength: r.MaxPathLength,
+			MaxPaths: r.MaxPaths, RulesOnly: r.RulesOnly, Constraint: r.Constraint, Timeout: r.Timeout, Limits: r.Limits, Stores: r.Stores,
+		},
+		Nodes: []sessionNode{},
+		Lines: []sessionLine{},
+	}
+	if c.Goal != nil {
+		s.Goal = korrel8r.ClassName(c.Goal)
+	}
+	lines := c.Graph.AllLines()
+	lineIndex := map[*graph.Line]int{}
+	for i, l := range lines {
+		lineIndex[l] = i
+	}
+	objectIndex := map[int64]map[any]int{} // Object indices by node ID and object ID.
+	for _, n := range c.Graph.AllNodes() {
+		sn := sessionNode{Class: korrel8r.ClassName(n.Class), Incomplete: n.Incomplete, Truncated: n.Truncated}
+		objectIndex[n.ID()] = map[any]int{}
+		for i, o := range n.Result.List() {
+			b, err := json.Marshal(o)
+			if err != nil {
+				return fmt.Errorf("%v: %w", sn.Class, err)
+			}
+			sn.Objects = append(sn.Objects, b)
+			id := korrel8r.ObjectID(n.Class, o)
+			objectIndex[n.ID()][id] = i
+			var origins []sessionOrigin
+			for _, origin := range n.Origins[id] {
+				so := sessionOrigin{Query: []byte(korrel8r.JSONString(origin.Query))}
+				if origin.Line != nil {
+					i, ok := lineIndex[origin.Line]
+					if !ok {
+						continue // Line is not in the graph.
+					}
+					so.Line = &i
+				}
+				origins = append(origins, so)
+			}
+			sn.Origins = append(sn.Origins, origins)
+		}
+		if slices.IndexFunc(sn.Origins, func(o []sessionOrigin) bool { return len(o) > 0 }) < 0 {
+			sn.Origins = nil
+		}
+		var err error
+		if sn.Queries, err = saveQueries(n.QueryCounts); err != nil {
+			return err
+		}
+		s.Nodes = append(s.Nodes, sn)
+	}
+	for _, l := range lines {
+		sl := sessionLine{
+			Rule:       l.Rule.String(),
+			Start:      korrel8r.ClassName(l.Rule.Start()),
+			Goal:       korrel8r.ClassName(l.Rule.Goal()),
+			Incomplete: l.Incomplete,
+			Truncated:  l.Truncated,
+		}
+		var err error
+		if sl.Queries, err = saveQueries(l.QueryCounts); err != nil {
+			return err
+		}
+		from := l.From().(*graph.Node)
+		for query, parents := range l.Parents {
+			sp := sessionParent{Query: []byte(query)}
+			for _, o := range parents {
+				if i, ok := objectIndex[from.ID()][korrel8r.ObjectID(from.Class, o)]; ok {
+					sp.Objects = append(sp.Objects, i)
+				}
+			}
+			sl.Parents = append(sl.Parents, sp)
+		}
+		slices.SortFunc(sl.Parents, func(a, b sessionParent) bool { return string(a.Query) < string(b.Query) })
+		s.Lines = append(s.Lines, sl)
+	}
+	for _, f := range c.Failures {
+		sf := sessionFailure{Failure: f}
+		if f.Query != nil {
+			b, err := json.Marshal(f.Query)
+			if err != nil {
+				return err
+			}
+			sf.Query = b
+		}
+		s.Failures = append(s.Failures, sf)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+func saveQueries(qcs graph.QueryCounts) (list []sessionQuery, err error) {
+	for _, qc := range qcs.Sort() {
+		b, err := json.Marshal(qc.Query)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, sessionQuery{Query: b, Count: qc.Count, Stores: qc.Stores})
+	}
+	return list, nil
+}
+
+// LoadCorrelation reads a correlation written by Correlation.Save.
+//
+// Classes and queries are decoded by the engine's domains, objects are decoded by their class, see korrel8r.UnmarshalObject.
+// Lines use the engine rule with the same name, start and goal if there is one.
+// Otherwise they use a placeholder rule that cannot be applied, so a correlation can be loaded with different rules.
+func (e *Engine) LoadCorrelation(r io.Reader) (*Correlation, error) {
+	var s session
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	c := &Correlation{Reverse: s.Reverse}
+	var err error
+	if c.Start, err = e.Class(s.Start); err != nil {
+		return nil, err
+	}
+	if s.Goal != "" {
+		if c.Goal, err = e.Class(s.Goal); err != nil {
+			return nil, err
+		}
+	}
+	o := s.Options
+	c.request = Request{
+		Start: c.Start, Goal: c.Goal, Reverse: c.Reverse,
+		Depth: o.Depth, ShortestPaths: o.ShortestPaths, KShortest: o.KShortest, MaxPathLength: o.MaxPathLength,
+		MaxPaths: o.MaxPaths, RulesOnly: o.RulesOnly, Constraint: o.Constraint, Timeout: o.Timeout, Limits: o.Limits, Stores: o.Stores,
+	}
+	rules := map[string]korrel8r.Rule{}
+	for _, r := range e.rules {
+		rules[korrel8r.RuleName(r)] = r
+	}
+	// Build lines first, so the graph Data has all the nodes they need.
+	type loadedLine struct {
+		rule korrel8r.Rule
+		sl   sessionLine
+	}
+	var loaded []loadedLine
+	var lineRules []korrel8r.Rule
+	for _, sl := range s.Lines {
+		start, err := e.Class(sl.Start)
+		if err != nil {
+			return nil, err
+		}
+		goal, err := e.Class(sl.Goal)
+		if err != nil {
+			return nil, err
+		}
+		var rule korrel8r.Rule = savedRule{name: sl.Rule, start: start, goal: goal}
+		if r, ok := rules[korrel8r.RuleName(rule)]; ok {
+			rule = r
+		}
+		loaded = append(loaded, loadedLine{rule: rule, sl: sl})
+		lineRules = append(lineRules, rule)
+	}
+	data := graph.NewData(lineRules...)
+	c.Graph = data.EmptyGraph()
+	for i, ll := range loaded {
+		l := data.Lines[i]
+		l.Incomplete, l.Truncated = ll.sl.Incomplete, ll.sl.Truncated
+		if err := loadQueries(ll.rule.Goal(), ll.sl.Queries, l.QueryCounts); err != nil {
+			return nil, fmt.Errorf("rule %v: %w", ll.sl.Rule, err)
+		}
+		c.Graph.SetLine(l)
+	}
+	objects := map[int64][]korrel8r.Object{} // Objects by node ID, in saved order.
+	for _, sn := range s.Nodes {
+		class, err := e.Class(sn.Class)
+		if err != nil {
+			return nil, err
+		}
+		n := data.NodeFor(class)
+		n.Incomplete, n.Truncated = sn.Incomplete, sn.Truncated
+		for i, b := range sn.Objects {
+			o, err := korrel8r.UnmarshalObject(class, b)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %w", sn.Class, err)
+			}
+			n.Result.Append(o)
+			objects[n.ID()] = append(objects[n.ID()], o)
+			if i >= len(sn.Origins) {
+				continue
+			}
+			for _, so := range sn.Origins[i] {
+				q, err := class.Domain().UnmarshalQuery(so.Query)
+				if err != nil {
+					return nil, fmt.Errorf("%v: %w", sn.Class, err)
+				}
+				origin := graph.Origin{Query: q}
+				if so.Line != nil {
+					if *so.Line < 0 || *so.Line >= len(data.Lines) {
+						return nil, fmt.Errorf("%v: invalid line index: %v", sn.Class, *so.Line)
+					}
+					origin.Line = data.Lines[*so.Line]
+				}
+				n.AddOrigin(o, origin)
+			}
+		}
+		if err := loadQueries(class, sn.Queries, n.QueryCounts); err != nil {
+			return nil, fmt.Errorf("%v: %w", sn.Class, err)
+		}
+		if c.Graph.Node(n.ID()) == nil {
+			c.Graph.AddNode(n)
+		}
+	}
+	for i, ll := range loaded {
+		l := data.Lines[i]
+		parents := objects[l.From().ID()]
+		for _, sp := range ll.sl.Parents {
+			q, err := ll.rule.Goal().Domain().UnmarshalQuery(sp.Query)
+			if err != nil {
+				return nil, fmt.Errorf("rule %v: %w", ll.sl.Rule, err)
+			}
+			for _, i := range sp.Objects {
+				if i < 0 || i >= len(parents) {
+					return nil, fmt.Errorf("rule %v: invalid object index: %v", ll.sl.Rule, i)
+				}
+				l.AddParent(q, parents[i])
+			}
+		}
+	}
+	for _, sf := range s.Failures {
+		f := sf.Failure
+		f.Err = errors.New(f.Msg)
+		if len(sf.Query) > 0 {
+			goal, err := e.Class(f.Goal)
+			if err != nil {
+				return nil, err
+			}
+			if f.Query, err = goal.Domain().UnmarshalQuery(sf.Query); err != nil {
+				return nil, err
+			}
+		}
+		c.Failures = append(c.Failures, f)
+	}
+	return c, nil
+}
+
+// loadQueries decodes saved queries for class into qcs.
+func loadQueries(class korrel8r.Class, saved []sessionQuery, qcs graph.QueryCounts) error {
+	for _, sq := range saved {
+		q, err := class.Domain().UnmarshalQuery(sq.Query)
+		if err != nil {
+			return err
+		}
+		qcs[korrel8r.JSONString(q)] = graph.QueryCount{Query: q, Count: sq.Count, Stores: sq.Stores}
+	}
+	return nil
+}
+
+// savedRule is a placeholder for a rule in a saved correlation that is not known to the engine.
+type savedRule struct {
+	name        string
+	start, goal korrel8r.Class
+}
+
+func (r savedRule) Start() korrel8r.Class { return r.start }
+func (r savedRule) Goal() korrel8r.Class  { return r.goal }
+func (r savedRule) String() string        { return r.name }
+func (r savedRule) Apply(korrel8r.Object, *korrel8r.Constraint) (korrel8r.Query, error) {
+	return nil, fmt.Errorf("rule not loaded: %v", r.name)
+}
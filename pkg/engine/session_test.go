@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/korrel8r/korrel8r/internal/pkg/test/mock"
+	"github.com/korrel8r/korrel8r/pkg/graph"
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCorrelation_SaveLoad(t *testing.T) {
+	s := mock.Store{}
+	e := New()
+	e.AddDomain(mock.Domain("mock"), s)
+	e.AddRules(
+		mock.NewRule("ab", "mock/a", "mock/b", follow(s, "mock/b")),
+		mock.NewRule("bc", "mock/b", "mock/c", follow(s, "mock/c")),
+	)
+	c, err := e.Correlate(context.Background(), Request{Queries: []korrel8r.Query{s.NewQuery("mock/a:1")}, Goal: mock.Class("mock/c")})
+	require.NoError(t, err)
+	c.Failures = Failures{newFailure(StoreFailed, e.Rules()[1], mock.Query("mock/c:2"), "", errors.New("oops"))}
+	c.Graph.NodeFor(mock.Class("mock/c")).Truncated = true
+
+	var b bytes.Buffer
+	require.NoError(t, c.Save(&b))
+	// Load into an engine with no stores and only one of the rules.
+	e2 := New()
+	e2.AddDomain(mock.Domain("mock"), nil)
+	e2.AddRules(e.Rules()[0])
+	c2, err := e2.LoadCorrelation(&b)
+	require.NoError(t, err)
+
+	assert.Equal(t, c.Start, c2.Start)
+	assert.Equal(t, c.Goal, c2.Goal)
+	assert.False(t, c2.Reverse)
+	assert.Equal(t, c.Nodes(), c2.Nodes())
+	assert.True(t, c2.Nodes()[2].Truncated)
+	assert.Equal(t, c.Failures.Error(), c2.Failures.Error())
+	assert.Equal(t, mock.Query("mock/c:2"), c2.Failures[0].Query)
+	provenance := func(c *Correlation) string {
+		return fmt.Sprintf("%v", c.Graph.NodeFor(mock.Class("mock/c")).Provenance(mock.Object("mock/c:1")))
+	}
+	assert.Equal(t, provenance(c), provenance(c2))
+	assert.Contains(t, provenance(c2), "bc")
+	rules := map[string]korrel8r.Rule{}
+	for _, l := range c2.Graph.AllLines() {
+		rules[l.Rule.String()] = l.Rule
+		assert.Equal(t, 1, l.QueryCounts.Total())
+	}
+	require.Len(t, rules, 2)
+	assert.IsType(t, mock.Rule{}, rules["ab"]) // Engine rule.
+	assert.Equal(t, "mock/c", korrel8r.ClassName(rules["bc"].Goal()))
+	_, err = rules["bc"].Apply(mock.Object("mock/b:1"), nil)
+	assert.EqualError(t, err, "rule not loaded: bc")
+}
+
+func TestCorrelation_SaveLoad_Reverse(t *testing.T) {
+	s := mock.Store{}
+	e := New()
+	e.AddDomain(mock.Domain("mock"), s)
+	e.AddRules(
+		mock.NewRule("ab", "mock/a", "mock/b", follow(s, "mock/b")),
+		mock.NewRule("cb", "mock/c", "mock/b", follow(s, "mock/b")),
+		mock.NewRule("ba", "mock/b", "mock/a", follow(s, "mock/a")),
+	)
+	limit := uint(10)
+	r := Request{Start: mock.Class("mock/b"), Objects: mock.Objects("mock/b:1"), Depth: 1, Reverse: true,
+		Constraint: &korrel8r.Constraint{Limit: &limit}, Limits: Limits{MaxObjects: 5}}
+	c, err := e.Correlate(context.Background(), r)
+	require.NoError(t, err)
+
+	var b bytes.Buffer
+	require.NoError(t, c.Save(&b))
+	c2, err := e.LoadCorrelation(&b)
+	require.NoError(t, err)
+
+	assert.True(t, c2.Reverse)
+	assert.ElementsMatch(t, classNames(c.Graph.AllNodes()), classNames(c2.Graph.AllNodes()))
+	assert.Contains(t, classNames(c2.Graph.AllNodes()), "mock/c", "upstream class without results")
+	r2 := c2.Request()
+	assert.True(t, r2.Reverse)
+	assert.Equal(t, 1, r2.Depth)
+	assert.Equal(t, r.Constraint, r2.Constraint)
+	assert.Equal(t, r.Limits, r2.Limits)
+	assert.Empty(t, r2.Objects)
+}
+
+func classNames(nodes []*graph.Node) (names []string) {
+	for _, n := range nodes {
+		names = append(names, korrel8r.ClassName(n.Class))
+	}
+	return names
+}
@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/korrel8r/korrel8r/pkg/graph"
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+)
+
+// Coster can be implemented by a korrel8r.Rule to declare the cost of following the rule.
+// Rules that do not implement Coster, or return a cost <= 0, have a cost of 1.
+type Coster interface{ Cost() float64 }
+
+// RuleStats are statistics for the queries generated by a rule.
+type RuleStats struct {
+	Queries int `json:"queries"` // Queries sent to stores.
+	Hits    int `json:"hits"`    // Queries that returned at least one result.
+	Results int `json:"results"` // Total results from all queries.
+}
+
+// HitRate estimates the probability that a query will return results.
+// With no history the estimate is 1/2.
+func (rs RuleStats) HitRate() float64 { return float64(rs.Hits+1) / float64(rs.Queries+2) }
+
+// Stats is a history of RuleStats, indexed by korrel8r.RuleName.
+// Stats can be saved and loaded as JSON, it is safe for concurrent use.
+type Stats struct {
+	mu    sync.Mutex
+	rules map[string]RuleStats
+}
+
+func NewStats() *Stats { return &Stats{rules: map[string]RuleStats{}} }
+
+// Get returns the statistics for a rule.
+func (s *Stats) Get(r korrel8r.Rule) RuleStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rules[korrel8r.RuleName(r)]
+}
+
+// Record adds the queries on the lines of g.
+// Incomplete lines are ignored, their counts are not reliable.
+func (s *Stats) Record(g *graph.Graph) { s.record(g, nil) }
+
+// record is like Record but ignores queries where skip(line, queryKey) is true.
+func (s *Stats) record(g *graph.Graph, skip func(*graph.Line, string) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g.EachLine(func(l *graph.Line) {
+		if l.Incomplete || len(l.QueryCounts) == 0 {
+			return
+		}
+		name := korrel8r.RuleName(l.Rule)
+		rs := s.rules[name]
+		for key, qc := range l.QueryCounts {
+			if skip != nil && skip(l, key) {
+				continue
+			}
+			rs.Queries++
+			rs.Results += qc.Count
+			if qc.Count > 0 {
+				rs.Hits++
+			}
+		}
+		s.rules[name] = rs
+	})
+}
+
+func (s *Stats) MarshalJSON() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return json.Marshal(s.rules)
+}
+
+func (s *Stats) UnmarshalJSON(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return json.Unmarshal(b, &s.rules)
+}
+
+// LoadStats loads statistics from a JSON file. Returns empty Stats if the file does not exist.
+func LoadStats(path string) (*Stats, error) {
+	s := NewStats()
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, json.Unmarshal(b, s)
+}
+
+// Save statistics to a JSON file.
+func (s *Stats) Save(path string) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	// Write a unique temporary file and rename it, so concurrent saves don't overwrite each other's
+	// temporary file, and a concurrent load never sees a partial file.
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // Fails harmlessly after a successful rename.
+	_, err = f.Write(b)
+	if err == nil {
+		err = f.Chmod(0664)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/korrel8r/korrel8r/internal/pkg/test/mock"
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleStats_HitRate(t *testing.T) {
+	assert.Equal(t, 0.5, RuleStats{}.HitRate())
+	assert.Equal(t, 0.25, RuleStats{Queries: 2}.HitRate())
+	assert.Equal(t, 0.75, RuleStats{Queries: 2, Hits: 2}.HitRate())
+}
+
+func TestEngine_Correlate_Stats(t *testing.T) {
+	s := mock.Store{}
+	e := New()
+	e.AddDomain(mock.Domain(""), s)
+	e.SetStats(NewStats())
+	query := func(objects ...string) mock.ApplyFunc {
+		return func(korrel8r.Object, *korrel8r.Constraint) (korrel8r.Query, error) {
+			return s.NewQuery(objects...), nil
+		}
+	}
+	ab, ac := mock.NewRule("ab", "a", "b", query()), mock.NewRule("ac", "a", "c", query("c:1"))
+	e.AddRules(ab, ac, mock.NewRule("bd", "b", "d", query("d:1")), mock.NewRule("cd", "c", "d", query("d:1")))
+	r := Request{Start: mock.Class("a"), Objects: mock.Objects("a:1"), Goal: mock.Class("d"), ShortestPaths: true}
+
+	// No history, both paths have the same cost.
+	assert.Equal(t, e.Cost(ab), e.Cost(ac))
+	_, err := e.Correlate(context.Background(), r)
+	require.NoError(t, err)
+	assert.Equal(t, RuleStats{Queries: 1}, e.Stats().Get(ab))
+	assert.Equal(t, RuleStats{Queries: 1, Hits: 1, Results: 1}, e.Stats().Get(ac))
+
+	// Rule ab did not return anything, prefer the path via c.
+	assert.Greater(t, e.Cost(ab), e.Cost(ac))
+	c, err := e.Correlate(context.Background(), r)
+	require.NoError(t, err)
+	assert.Equal(t, []korrel8r.Class{mock.Class("a"), mock.Class("c"), mock.Class("d")}, nodeClasses(c))
+	assert.Equal(t, RuleStats{Queries: 1}, e.Stats().Get(ab), "rule ab not followed")
+	// Adding objects does not record the same queries again.
+	require.NoError(t, c.Add(context.Background(), nil, mock.Objects("a:2")))
+	assert.Equal(t, RuleStats{Queries: 2, Hits: 2, Results: 2}, e.Stats().Get(ac))
+
+	// Statistics persist.
+	path := filepath.Join(t.TempDir(), "stats.json")
+	require.NoError(t, e.Stats().Save(path))
+	loaded, err := LoadStats(path)
+	require.NoError(t, err)
+	assert.Equal(t, e.Stats().Get(ab), loaded.Get(ab))
+	assert.Equal(t, RuleStats{Queries: 2, Hits: 2, Results: 2}, loaded.Get(ac))
+	empty, err := LoadStats(filepath.Join(t.TempDir(), "nonesuch.json"))
+	require.NoError(t, err)
+	assert.Equal(t, RuleStats{}, empty.Get(ab))
+}
+
+func TestStats_SaveConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "stats.json")
+	s := NewStats()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, s.Save(path))
+		}()
+	}
+	wg.Wait()
+	_, err := LoadStats(path)
+	require.NoError(t, err)
+	// No temporary files are left behind.
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+}
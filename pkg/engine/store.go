@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/korrel8r/korrel8r/pkg/cache"
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+)
+
+// storeEntry is a store added to the engine, with the wrappers used by Engine.Get and Follower.
+type storeEntry struct {
+	id, name string
+	store    korrel8r.Store // Original store, returned by Engine.Store.
+	limit    chan struct{}  // Semaphore limiting concurrent Get calls, may be nil.
+	timeout  time.Duration  // Timeout for Get calls, 0 means no timeout.
+	cache    *cache.Store   // Result cache, may be nil.
+	get      korrel8r.Store // Store with wrappers applied.
+}
+
+// update re-builds the wrapped store after a change to the entry.
+//
+// Wrappers are applied in this order: cache, timeout, concurrency limit.
+// The timeout includes time spent waiting for the concurrency limit.
+// Wrappers are not returned by Engine.Store, they would hide optional interfaces implemented by the store.
+func (se *storeEntry) update() {
+	s := se.store
+	if se.limit != nil {
+		s = &limitStore{Store: s, sem: se.limit}
+	}
+	if se.timeout > 0 {
+		s = &timeoutStore{Store: s, timeout: se.timeout}
+	}
+	if se.cache != nil {
+		se.cache.Store = s
+		s = se.cache
+	}
+	se.get = s
+}
+
+// storeResult is the result of a Get call to one store.
+type storeResult struct {
+	id      string
+	objects []korrel8r.Object
+	err     error
+}
+
+// getEach gets query results from each store concurrently.
+// Results are returned in the same order as entries.
+func getEach(ctx context.Context, entries []*storeEntry, q korrel8r.Query, c *korrel8r.Constraint) []storeResult {
+	results := make([]storeResult, len(entries))
+	var wg sync.WaitGroup
+	for i, se := range entries {
+		i, se := i, se
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := korrel8r.NewListResult()
+			err := ctx.Err() // Don't start new queries after the deadline.
+			if err == nil {
+				err = se.get.Get(ctx, q, c, r)
+			}
+			results[i] = storeResult{id: se.id, objects: r.List(), err: err}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// timeoutStore applies a timeout to each Get call.
+type timeoutStore struct {
+	korrel8r.Store
+	timeout time.Duration
+}
+
+func (s *timeoutStore) Get(ctx context.Context, q korrel8r.Query, c *korrel8r.Constraint, r korrel8r.Appender) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.Store.Get(ctx, q, c, r)
+}
+
+// limitStore limits concurrent Get calls to a store using a shared semaphore.
+type limitStore struct {
+	korrel8r.Store
+	sem chan struct{}
+}
+
+func (s *limitStore) Get(ctx context.Context, q korrel8r.Query, c *korrel8r.Constraint, r korrel8r.Appender) error {
+	select {
+	case s.sem <- struct{}{}:
+		defer func() { <-s.sem }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return s.Store.Get(ctx, q, c, r)
+}
@@ -25,6 +25,7 @@ func NewData(rules ...korrel8r.Rule) *Data {
 	return &d
 }
 
+// AddRule adds a line for r. The start and goal classes of r must not be nil.
 func (d *Data) AddRule(r korrel8r.Rule) {
 	id := int64(len(d.Lines))
 	l := &Line{
@@ -32,6 +33,7 @@ func (d *Data) AddRule(r korrel8r.Rule) {
 		Rule:        r,
 		Attrs:       Attrs{},
 		QueryCounts: QueryCounts{},
+		Parents:     map[string][]korrel8r.Object{},
 	}
 	d.Lines = append(d.Lines, l)
 }
@@ -48,6 +50,7 @@ func (d *Data) NodeFor(c korrel8r.Class) *Node {
 		Attrs:       Attrs{},
 		Result:      korrel8r.NewResult(c),
 		QueryCounts: QueryCounts{},
+		Origins:     map[any][]Origin{},
 	}
 	d.Nodes = append(d.Nodes, n)
 	d.nodeID[c] = id
@@ -96,6 +99,10 @@ type Node struct {
 	Class       korrel8r.Class
 	Result      korrel8r.Result // Accumulate query results.
 	QueryCounts QueryCounts     // All queries leading to this node.
+	Incomplete  bool            // Some queries leading to this node failed or timed out.
+	Truncated   bool            // Some results or queries were dropped because of a limit, there is more data.
+	// Origins records how objects in Result were found, by korrel8r.ObjectID. See Provenance.
+	Origins map[any][]Origin
 }
 
 func ClassFor(n graph.Node) korrel8r.Class { return n.(*Node).Class }
@@ -109,6 +116,10 @@ type Line struct {
 	Attrs       // GraphViz Attributer
 	Rule        korrel8r.Rule
 	QueryCounts QueryCounts // Queries generated by Rule
+	Incomplete  bool        // Some queries generated by Rule failed or timed out.
+	Truncated   bool        // Some queries generated by Rule were not sent because of a limit.
+	// Parents are the start objects that generated each query, by korrel8r.JSONString of the query.
+	Parents map[string][]korrel8r.Object
 }
 
 func (l *Line) DOTID() string            { return l.Rule.String() }
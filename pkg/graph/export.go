@@ -0,0 +1,191 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+	"golang.org/x/exp/slices"
+	"gonum.org/v1/gonum/graph/encoding/dot"
+)
+
+// Export formats for Graph.Encode.
+const (
+	FormatDOT       = "dot"       // Graphviz DOT, including Attrs.
+	FormatJSON      = "json"      // See JSONGraph.
+	FormatCytoscape = "cytoscape" // Cytoscape.js elements, see CytoscapeElement.
+	FormatMermaid   = "mermaid"   // Mermaid flowchart.
+)
+
+// Formats lists the formats supported by Encode.
+var Formats = []string{FormatDOT, FormatJSON, FormatCytoscape, FormatMermaid}
+
+// Encode writes the graph to w in one of the Formats.
+func (g *Graph) Encode(w io.Writer, format string) error {
+	switch format {
+	case FormatDOT:
+		b, err := dot.MarshalMulti(g, "", "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	case FormatJSON:
+		return json.NewEncoder(w).Encode(g.JSON())
+	case FormatCytoscape:
+		return json.NewEncoder(w).Encode(g.Cytoscape())
+	case FormatMermaid:
+		return g.WriteMermaid(w)
+	default:
+		return fmt.Errorf("invalid graph format: %v", format)
+	}
+}
+
+// JSONGraph is a JSON model of a graph, for use by other tools.
+type JSONGraph struct {
+	Nodes []JSONNode `json:"nodes"`
+	Edges []JSONEdge `json:"edges"`
+}
+
+// JSONNode is a class node, see Node.
+type JSONNode struct {
+	Class      string       `json:"class"`             // Full class name, 'domain/class'
+	Count      int          `json:"count"`             // Number of objects in the node Result.
+	Queries    []QueryCount `json:"queries,omitempty"` // Queries leading to this node, by decreasing count.
+	Incomplete bool         `json:"incomplete,omitempty"`
+	Truncated  bool         `json:"truncated,omitempty"`
+}
+
+// JSONEdge is a rule line, see Line.
+type JSONEdge struct {
+	Start      string       `json:"start"` // Start class name.
+	Goal       string       `json:"goal"`  // Goal class name.
+	Rule       string       `json:"rule"`
+	Queries    []QueryCount `json:"queries,omitempty"` // Queries generated by the rule, by decreasing count.
+	Incomplete bool         `json:"incomplete,omitempty"`
+	Truncated  bool         `json:"truncated,omitempty"`
+}
+
+// JSON returns the JSON model of the graph. Nodes and edges are in ID order.
+func (g *Graph) JSON() *JSONGraph {
+	jg := &JSONGraph{Nodes: []JSONNode{}, Edges: []JSONEdge{}}
+	for _, n := range g.sortedNodes() {
+		jg.Nodes = append(jg.Nodes, JSONNode{
+			Class:      korrel8r.ClassName(n.Class),
+			Count:      len(n.Result.List()),
+			Queries:    n.QueryCounts.Sort(),
+			Incomplete: n.Incomplete,
+			Truncated:  n.Truncated,
+		})
+	}
+	for _, l := range g.sortedLines() {
+		jg.Edges = append(jg.Edges, JSONEdge{
+			Start:      korrel8r.ClassName(l.Rule.Start()),
+			Goal:       korrel8r.ClassName(l.Rule.Goal()),
+			Rule:       l.Rule.String(),
+			Queries:    l.QueryCounts.Sort(),
+			Incomplete: l.Incomplete,
+			Truncated:  l.Truncated,
+		})
+	}
+	return jg
+}
+
+// CytoscapeElement is a Cytoscape.js graph element, a node or an edge.
+// A list of elements can be passed as the `elements` option to cytoscape().
+type CytoscapeElement struct {
+	Group   string        `json:"group"` // "nodes" or "edges"
+	Data    CytoscapeData `json:"data"`
+	Classes []string      `json:"classes,omitempty"` // "incomplete" and "truncated" for incomplete and truncated nodes and edges.
+}
+
+// CytoscapeData is the data for a Cytoscape.js element.
+type CytoscapeData struct {
+	ID     string `json:"id"`
+	Label  string `json:"label"`
+	Source string `json:"source,omitempty"` // Source node ID for edges.
+	Target string `json:"target,omitempty"` // Target node ID for edges.
+	Count  int    `json:"count"`            // Objects for nodes, total query results for edges.
+}
+
+// Cytoscape returns the graph as Cytoscape.js elements.
+// Node IDs are class names, edge IDs are "e" followed by the line ID.
+func (g *Graph) Cytoscape() []CytoscapeElement {
+	elements := []CytoscapeElement{}
+	classes := func(incomplete, truncated bool) (classes []string) {
+		if incomplete {
+			classes = append(classes, "incomplete")
+		}
+		if truncated {
+			classes = append(classes, "truncated")
+		}
+		return classes
+	}
+	for _, n := range g.sortedNodes() {
+		name := korrel8r.ClassName(n.Class)
+		elements = append(elements, CytoscapeElement{
+			Group:   "nodes",
+			Data:    CytoscapeData{ID: name, Label: name, Count: len(n.Result.List())},
+			Classes: classes(n.Incomplete, n.Truncated),
+		})
+	}
+	for _, l := range g.sortedLines() {
+		elements = append(elements, CytoscapeElement{
+			Group: "edges",
+			Data: CytoscapeData{
+				ID:     fmt.Sprintf("e%v", l.ID()),
+				Label:  l.Rule.String(),
+				Source: korrel8r.ClassName(l.Rule.Start()),
+				Target: korrel8r.ClassName(l.Rule.Goal()),
+				Count:  l.QueryCounts.Total(),
+			},
+			Classes: classes(l.Incomplete, l.Truncated),
+		})
+	}
+	return elements
+}
+
+// WriteMermaid writes the graph as a Mermaid flowchart.
+// Labels include result counts, incomplete nodes and edges are styled with the "incomplete" class.
+func (g *Graph) WriteMermaid(w io.Writer) error {
+	b := &strings.Builder{}
+	fmt.Fprintln(b, "flowchart LR")
+	var incomplete []string
+	nodeID := func(n *Node) string { return fmt.Sprintf("n%v", n.ID()) }
+	for _, n := range g.sortedNodes() {
+		fmt.Fprintf(b, "  %v[\"%v (%v)\"]\n", nodeID(n), mermaidEscape(korrel8r.ClassName(n.Class)), len(n.Result.List()))
+		if n.Incomplete {
+			incomplete = append(incomplete, nodeID(n))
+		}
+	}
+	for i, l := range g.sortedLines() {
+		fmt.Fprintf(b, "  %v -->|\"%v (%v)\"| %v\n",
+			nodeID(l.From().(*Node)), mermaidEscape(l.Rule.String()), l.QueryCounts.Total(), nodeID(l.To().(*Node)))
+		if l.Incomplete {
+			fmt.Fprintf(b, "  linkStyle %v stroke:orange\n", i)
+		}
+	}
+	if len(incomplete) > 0 {
+		fmt.Fprintln(b, "  classDef incomplete stroke:orange,stroke-dasharray:5")
+		fmt.Fprintf(b, "  class %v incomplete\n", strings.Join(incomplete, ","))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mermaidEscape escapes characters that are not allowed in quoted Mermaid labels.
+func mermaidEscape(s string) string { return strings.ReplaceAll(s, `"`, "#quot;") }
+
+func (g *Graph) sortedNodes() []*Node {
+	nodes := g.AllNodes()
+	slices.SortFunc(nodes, func(a, b *Node) bool { return a.ID() < b.ID() })
+	return nodes
+}
+
+func (g *Graph) sortedLines() []*Line {
+	lines := g.AllLines()
+	slices.SortFunc(lines, func(a, b *Line) bool { return a.ID() < b.ID() })
+	return lines
+}
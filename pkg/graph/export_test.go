@@ -0,0 +1,79 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/korrel8r/korrel8r/internal/pkg/test/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exportGraph() *Graph {
+	g := testGraph([]rule{r(1, 2), r(2, 3)})
+	n2 := g.NodeFor(class(2))
+	n2.Result.Append(mock.Objects("x:a", "x:b")...)
+	n2.QueryCounts.Put(mock.Query("q"), 2)
+	g.EachLine(func(l *Line) {
+		if l.Rule == r(1, 2) {
+			l.QueryCounts.Put(mock.Query("q"), 2)
+		} else {
+			l.Incomplete = true
+		}
+	})
+	g.NodeFor(class(3)).Incomplete = true
+	return g
+}
+
+func TestGraph_JSON(t *testing.T) {
+	b, err := json.Marshal(exportGraph().JSON())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "nodes": [
+    {"class": "test/1", "count": 0},
+    {"class": "test/2", "count": 2, "queries": [{"query": "q", "count": 2}]},
+    {"class": "test/3", "count": 0, "incomplete": true}
+  ],
+  "edges": [
+    {"start": "test/1", "goal": "test/2", "rule": "(1,2)", "queries": [{"query": "q", "count": 2}]},
+    {"start": "test/2", "goal": "test/3", "rule": "(2,3)", "incomplete": true}
+  ]
+}`, string(b))
+}
+
+func TestGraph_Cytoscape(t *testing.T) {
+	b, err := json.Marshal(exportGraph().Cytoscape())
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+  {"group": "nodes", "data": {"id": "test/1", "label": "test/1", "count": 0}},
+  {"group": "nodes", "data": {"id": "test/2", "label": "test/2", "count": 2}},
+  {"group": "nodes", "data": {"id": "test/3", "label": "test/3", "count": 0}, "classes": ["incomplete"]},
+  {"group": "edges", "data": {"id": "e0", "label": "(1,2)", "source": "test/1", "target": "test/2", "count": 2}},
+  {"group": "edges", "data": {"id": "e1", "label": "(2,3)", "source": "test/2", "target": "test/3", "count": 0}, "classes": ["incomplete"]}
+]`, string(b))
+}
+
+func TestGraph_Mermaid(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, exportGraph().Encode(&b, FormatMermaid))
+	assert.Equal(t, `flowchart LR
+  n0["test/1 (0)"]
+  n1["test/2 (2)"]
+  n2["test/3 (0)"]
+  n0 -->|"(1,2) (2)"| n1
+  n1 -->|"(2,3) (0)"| n2
+  linkStyle 1 stroke:orange
+  classDef incomplete stroke:orange,stroke-dasharray:5
+  class n2 incomplete
+`, b.String())
+}
+
+func TestGraph_Encode(t *testing.T) {
+	for _, format := range Formats {
+		var b bytes.Buffer
+		assert.NoError(t, exportGraph().Encode(&b, format), format)
+		assert.NotEmpty(t, b.String(), format)
+	}
+	assert.EqualError(t, exportGraph().Encode(&bytes.Buffer{}, "nonesuch"), "invalid graph format: nonesuch")
+}
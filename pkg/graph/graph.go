@@ -1,6 +1,8 @@
 package graph
 
 import (
+	"math"
+
 	"github.com/korrel8r/korrel8r/pkg/korrel8r"
 	"github.com/korrel8r/korrel8r/pkg/unique"
 
@@ -8,6 +10,7 @@ import (
 	"gonum.org/v1/gonum/graph/encoding"
 	"gonum.org/v1/gonum/graph/multi"
 	"gonum.org/v1/gonum/graph/path"
+	"gonum.org/v1/gonum/graph/simple"
 )
 
 // Graph is a directed multigraph with korrel8r.Class noes and korrel8r.Rule lines.
@@ -152,43 +155,48 @@ func (g *Graph) ShortestPaths(start, goal korrel8r.Class) *Graph {
 	return g.newPaths(paths)
 }
 
-// AllPaths returns a new sub-graph containing all paths between start and goal.
-func (g *Graph) AllPaths(start, goal korrel8r.Class) *Graph {
-	u, v := g.NodeFor(start), g.NodeFor(goal)
-	ap := allPaths{
-		g:       g,
-		visited: map[int64]bool{u.ID(): true},
-		path:    []graph.Node{u},
-	}
-	ap.run(u.ID(), v.ID())
-	return g.newPaths(ap.paths)
+// CheapestPaths returns a new sub-graph containing all lowest-cost paths between start and goal.
+// The cost of a path is the sum of cost(line) for its lines, cost must be > 0.
+// If there are several lines between two nodes, the cheapest one is used to find paths,
+// but all the lines are included in the sub-graph.
+func (g *Graph) CheapestPaths(start, goal korrel8r.Class, cost func(*Line) float64) *Graph {
+	shortest := path.DijkstraAllPaths(weighted{Graph: g, cost: cost})
+	paths, _ := shortest.AllBetween(g.NodeFor(start).ID(), g.NodeFor(goal).ID())
+	return g.newPaths(paths)
 }
 
-// allPaths is the state of a backtracking depth-first-search
-type allPaths struct {
-	g       graph.Graph
-	visited map[int64]bool
-	path    []graph.Node
-	paths   [][]graph.Node
+var _ graph.Weighted = weighted{}
+
+// weighted implements graph.Weighted using a cost function for lines.
+type weighted struct {
+	*Graph
+	cost func(*Line) float64
 }
 
-func (ap *allPaths) run(u, v int64) {
-	iter := ap.g.From(u)
-	for iter.Next() {
-		n := iter.Node()
-		if ap.visited[n.ID()] {
-			continue
-		}
-		ap.path = append(ap.path, n)
-		if n.ID() == v { // Complete path
-			path := make([]graph.Node, len(ap.path))
-			copy(path, ap.path)
-			ap.paths = append(ap.paths, path)
-		} else { // Continue search
-			ap.visited[n.ID()] = true
-			ap.run(n.ID(), v)
-			ap.visited[n.ID()] = false
-		}
-		ap.path = ap.path[0 : len(ap.path)-1] // Backtrack and continue search.
+func (w weighted) WeightedEdge(uid, vid int64) graph.WeightedEdge {
+	e := w.Edge(uid, vid)
+	if e == nil {
+		return nil
 	}
+	c, _ := w.Weight(uid, vid)
+	return simple.WeightedEdge{F: e.From(), T: e.To(), W: c}
+}
+
+func (w weighted) Weight(xid, yid int64) (float64, bool) {
+	if xid == yid {
+		return 0, true
+	}
+	min, ok := math.Inf(1), false
+	lines := w.Lines(xid, yid)
+	for lines.Next() {
+		ok = true
+		min = math.Min(min, w.cost(lines.Line().(*Line)))
+	}
+	return min, ok
+}
+
+// AllPaths returns a new sub-graph containing all paths between start and goal.
+// See BoundedPaths to limit the number and length of paths for large graphs.
+func (g *Graph) AllPaths(start, goal korrel8r.Class) *Graph {
+	return g.BoundedPaths(start, goal, PathOptions{})
 }
@@ -0,0 +1,179 @@
+package graph
+
+import (
+	"container/heap"
+
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+	"golang.org/x/exp/slices"
+	"gonum.org/v1/gonum/graph"
+)
+
+// PathOptions limit the paths found by BoundedPaths. Zero values mean no limit.
+type PathOptions struct {
+	MaxLength int // Max number of lines in a path.
+	MaxCount  int // Max number of paths.
+}
+
+// BoundedPaths returns a new sub-graph containing paths between start and goal, limited by opts.
+//
+// The search only visits nodes that can reach goal within the remaining path length,
+// so dead ends do not slow it down. Nodes closer to the goal are visited first,
+// so if MaxCount stops the search, shorter paths are more likely to be included.
+func (g *Graph) BoundedPaths(start, goal korrel8r.Class, opts PathOptions) *Graph {
+	u, v := g.NodeFor(start), g.NodeFor(goal)
+	ap := allPaths{
+		g:       g,
+		opts:    opts,
+		dist:    g.distanceTo(v.ID()),
+		visited: map[int64]bool{u.ID(): true},
+		path:    []graph.Node{u},
+	}
+	if _, ok := ap.dist[u.ID()]; ok {
+		ap.run(u.ID(), v.ID())
+	}
+	return g.newPaths(ap.paths)
+}
+
+// allPaths is the state of a backtracking depth-first-search
+type allPaths struct {
+	g       *Graph
+	opts    PathOptions
+	dist    map[int64]int // Lines on the shortest path to the goal, by node ID.
+	visited map[int64]bool
+	path    []graph.Node
+	paths   [][]graph.Node
+}
+
+// done is true if the search has found enough paths.
+func (ap *allPaths) done() bool { return ap.opts.MaxCount > 0 && len(ap.paths) >= ap.opts.MaxCount }
+
+func (ap *allPaths) run(u, v int64) {
+	var next []graph.Node
+	for iter := ap.g.From(u); iter.Next(); {
+		n := iter.Node()
+		d, ok := ap.dist[n.ID()]
+		if !ok || ap.visited[n.ID()] {
+			continue // Can't reach the goal, or already on the path.
+		}
+		if ap.opts.MaxLength > 0 && len(ap.path)+d > ap.opts.MaxLength {
+			continue // Can't reach the goal within MaxLength.
+		}
+		next = append(next, n)
+	}
+	slices.SortFunc(next, func(a, b graph.Node) bool {
+		da, db := ap.dist[a.ID()], ap.dist[b.ID()]
+		return da < db || (da == db && a.ID() < b.ID())
+	})
+	for _, n := range next {
+		if ap.done() {
+			return
+		}
+		ap.path = append(ap.path, n)
+		if n.ID() == v { // Complete path
+			path := make([]graph.Node, len(ap.path))
+			copy(path, ap.path)
+			ap.paths = append(ap.paths, path)
+		} else { // Continue search
+			ap.visited[n.ID()] = true
+			ap.run(n.ID(), v)
+			ap.visited[n.ID()] = false
+		}
+		ap.path = ap.path[0 : len(ap.path)-1] // Backtrack and continue search.
+	}
+}
+
+// distanceTo returns the number of lines on the shortest path from each node to v.
+// Nodes that can't reach v are not included.
+func (g *Graph) distanceTo(v int64) map[int64]int {
+	dist := map[int64]int{v: 0}
+	queue := []int64{v}
+	for len(queue) > 0 {
+		u := queue[0]
+		queue = queue[1:]
+		for iter := g.To(u); iter.Next(); {
+			id := iter.Node().ID()
+			if _, ok := dist[id]; !ok {
+				dist[id] = dist[u] + 1
+				queue = append(queue, id)
+			}
+		}
+	}
+	return dist
+}
+
+// KShortestPaths returns a new sub-graph containing the k lowest-cost paths between start and goal.
+// The cost of a path is the sum of cost(line) for its lines, cost must be > 0.
+// Paths with the same cost as the k'th path may not be included.
+func (g *Graph) KShortestPaths(start, goal korrel8r.Class, k int, cost func(*Line) float64) *Graph {
+	u, v := g.NodeFor(start), g.NodeFor(goal)
+	w := weighted{Graph: g, cost: cost}
+	h := w.costTo(v.ID()) // Exact remaining cost, guides the search directly towards the goal.
+	var paths [][]graph.Node
+	if _, ok := h[u.ID()]; !ok || k <= 0 {
+		return g.newPaths(nil)
+	}
+	// Best-first search of partial paths, ordered by cost so far + remaining cost.
+	q := &pathQueue{{path: []graph.Node{u}, priority: h[u.ID()]}}
+	for q.Len() > 0 && len(paths) < k {
+		p := heap.Pop(q).(pathItem)
+		last := p.path[len(p.path)-1]
+		if last.ID() == v.ID() {
+			paths = append(paths, p.path)
+			continue
+		}
+		for iter := g.From(last.ID()); iter.Next(); {
+			n := iter.Node()
+			remaining, ok := h[n.ID()]
+			if !ok || slices.IndexFunc(p.path, func(x graph.Node) bool { return x.ID() == n.ID() }) >= 0 {
+				continue // Can't reach goal, or would make a loop.
+			}
+			c, _ := w.Weight(last.ID(), n.ID())
+			path := append(slices.Clone(p.path), n)
+			heap.Push(q, pathItem{path: path, cost: p.cost + c, priority: p.cost + c + remaining})
+		}
+	}
+	return g.newPaths(paths)
+}
+
+// costTo returns the cost of the cheapest path from each node to v, using Dijkstra's algorithm on reversed lines.
+// Nodes that can't reach v are not included.
+func (w weighted) costTo(v int64) map[int64]float64 {
+	done := map[int64]float64{}
+	q := &pathQueue{{path: []graph.Node{w.Node(v)}}}
+	for q.Len() > 0 {
+		p := heap.Pop(q).(pathItem)
+		u := p.path[0].ID()
+		if _, ok := done[u]; ok {
+			continue
+		}
+		done[u] = p.cost
+		for iter := w.To(u); iter.Next(); {
+			n := iter.Node()
+			if _, ok := done[n.ID()]; !ok {
+				c, _ := w.Weight(n.ID(), u)
+				heap.Push(q, pathItem{path: []graph.Node{n}, cost: p.cost + c, priority: p.cost + c})
+			}
+		}
+	}
+	return done
+}
+
+// pathItem is a partial path in a pathQueue.
+type pathItem struct {
+	path           []graph.Node
+	cost, priority float64
+}
+
+// pathQueue is a priority queue of paths, lowest priority first.
+type pathQueue []pathItem
+
+func (q pathQueue) Len() int           { return len(q) }
+func (q pathQueue) Less(i, j int) bool { return q[i].priority < q[j].priority }
+func (q pathQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x any)        { *q = append(*q, x.(pathItem)) }
+func (q *pathQueue) Pop() any {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+	"golang.org/x/exp/slices"
+)
+
+// Origin is the line and query that found an object.
+type Origin struct {
+	Line  *Line          // Line of the rule that generated Query, nil if Query was a start query.
+	Query korrel8r.Query // Query that returned the object.
+}
+
+// AddOrigin records that object o was found by origin, see Provenance.
+// Not safe for concurrent use.
+func (n *Node) AddOrigin(o korrel8r.Object, origin Origin) {
+	id := korrel8r.ObjectID(n.Class, o)
+	query := korrel8r.JSONString(origin.Query)
+	if slices.IndexFunc(n.Origins[id], func(x Origin) bool { return x.Line == origin.Line && korrel8r.JSONString(x.Query) == query }) < 0 {
+		n.Origins[id] = append(n.Origins[id], origin)
+	}
+}
+
+// AddParent records that the rule of l generated query from the start object parent.
+// Not safe for concurrent use.
+func (l *Line) AddParent(query korrel8r.Query, parent korrel8r.Object) {
+	key := korrel8r.JSONString(query)
+	l.Parents[key] = append(l.Parents[key], parent)
+}
+
+// Step is one step in the provenance of an object.
+type Step struct {
+	Class  korrel8r.Class
+	Object korrel8r.Object
+	Rule   korrel8r.Rule  // Rule applied to the object of the previous step, nil for the first step.
+	Query  korrel8r.Query // Query that returned Object, nil if Object was a start object.
+}
+
+// Provenance returns the chains of steps that found object o in node n.
+// Each chain starts with a start object and ends with o.
+// Objects with no recorded origins are start objects, their chain has a single step.
+func (n *Node) Provenance(o korrel8r.Object) [][]Step {
+	return n.provenance(o, map[nodeObject]bool{})
+}
+
+type nodeObject struct {
+	node int64
+	id   any
+}
+
+func (n *Node) provenance(o korrel8r.Object, visiting map[nodeObject]bool) (chains [][]Step) {
+	id := korrel8r.ObjectID(n.Class, o)
+	origins := n.Origins[id]
+	if len(origins) == 0 {
+		return [][]Step{{{Class: n.Class, Object: o}}}
+	}
+	key := nodeObject{node: n.ID(), id: id}
+	if visiting[key] {
+		return nil // Cycle
+	}
+	visiting[key] = true
+	defer delete(visiting, key)
+	for _, origin := range origins {
+		step := Step{Class: n.Class, Object: o, Query: origin.Query}
+		if origin.Line == nil {
+			chains = append(chains, []Step{step})
+			continue
+		}
+		step.Rule = origin.Line.Rule
+		from := origin.Line.From().(*Node)
+		for _, parent := range origin.Line.Parents[korrel8r.JSONString(origin.Query)] {
+			for _, chain := range from.provenance(parent, visiting) {
+				chains = append(chains, append(slices.Clone(chain), step))
+			}
+		}
+	}
+	return chains
+}
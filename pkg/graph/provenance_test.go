@@ -0,0 +1,42 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/korrel8r/korrel8r/internal/pkg/test/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNode_Provenance(t *testing.T) {
+	g := testGraph([]rule{{1, 2}, {2, 3}, {1, 3}, {3, 2}})
+	lines := map[rule]*Line{}
+	g.EachLine(func(l *Line) { lines[l.Rule.(rule)] = l })
+	n1, n2, n3 := g.NodeFor(class(1)), g.NodeFor(class(2)), g.NodeFor(class(3))
+
+	// Start object "x" found by query "q1", start object "y" given directly.
+	n1.AddOrigin("x", Origin{Query: mock.Query("q1")})
+	// x -(1,2)-> x2 -(2,3)-> x3, and y -(1,3)-> x3
+	lines[r(1, 2)].AddParent(mock.Query("q2"), "x")
+	n2.AddOrigin("x2", Origin{Line: lines[r(1, 2)], Query: mock.Query("q2")})
+	lines[r(2, 3)].AddParent(mock.Query("q3"), "x2")
+	n3.AddOrigin("x3", Origin{Line: lines[r(2, 3)], Query: mock.Query("q3")})
+	lines[r(1, 3)].AddParent(mock.Query("q13"), "y")
+	n3.AddOrigin("x3", Origin{Line: lines[r(1, 3)], Query: mock.Query("q13")})
+	n3.AddOrigin("x3", Origin{Line: lines[r(1, 3)], Query: mock.Query("q13")}) // Duplicate ignored.
+	// Cycle: x3 -(3,2)-> x2
+	lines[r(3, 2)].AddParent(mock.Query("q2"), "x3")
+	n2.AddOrigin("x2", Origin{Line: lines[r(3, 2)], Query: mock.Query("q2")})
+
+	assert.Equal(t, [][]Step{{{Class: class(1), Object: "y"}}}, n1.Provenance("y"))
+	assert.Equal(t, [][]Step{
+		{
+			{Class: class(1), Object: "x", Query: mock.Query("q1")},
+			{Class: class(2), Object: "x2", Rule: r(1, 2), Query: mock.Query("q2")},
+			{Class: class(3), Object: "x3", Rule: r(2, 3), Query: mock.Query("q3")},
+		},
+		{
+			{Class: class(1), Object: "y"},
+			{Class: class(3), Object: "x3", Rule: r(1, 3), Query: mock.Query("q13")},
+		},
+	}, n3.Provenance("x3"))
+}
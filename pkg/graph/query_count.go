@@ -11,15 +11,34 @@ type QueryCounts map[string]QueryCount
 
 // QueryCount is a query and a count of the data items it returned.
 type QueryCount struct {
-	Query korrel8r.Query
-	Count int
+	Query korrel8r.Query `json:"query"`
+	Count int            `json:"count"`
+	// Stores counts the items returned by each store, if the query was sent to stores.
+	Stores map[string]int `json:"stores,omitempty"`
 }
 
 func (qcs QueryCounts) Get(q korrel8r.Query) (QueryCount, bool) {
 	qc, ok := qcs[korrel8r.JSONString(q)]
 	return qc, ok
 }
-func (qcs QueryCounts) Put(q korrel8r.Query, c int) { qcs[korrel8r.JSONString(q)] = QueryCount{q, c} }
+func (qcs QueryCounts) Put(q korrel8r.Query, c int) {
+	qcs[korrel8r.JSONString(q)] = QueryCount{Query: q, Count: c}
+}
+
+// Add adds the count of items returned by store for query q.
+func (qcs QueryCounts) Add(q korrel8r.Query, store string, c int) {
+	key := korrel8r.JSONString(q)
+	qc, ok := qcs[key]
+	if !ok {
+		qc.Query = q
+	}
+	if qc.Stores == nil {
+		qc.Stores = map[string]int{}
+	}
+	qc.Count += c
+	qc.Stores[store] += c
+	qcs[key] = qc
+}
 
 // Total the counts
 func (qcs QueryCounts) Total() int {
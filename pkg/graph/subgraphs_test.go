@@ -39,3 +39,73 @@ func Test_allPaths(t *testing.T) {
 		})
 	}
 }
+
+func TestGraph_CheapestPaths(t *testing.T) {
+	g := testGraph([]rule{r(1, 2), r(2, 13), r(1, 3), r(3, 13), r(1, 13)})
+	costs := map[rule]float64{r(1, 13): 5, r(1, 2): 2}
+	cost := func(l *Line) float64 {
+		if c, ok := costs[l.Rule.(rule)]; ok {
+			return c
+		}
+		return 1
+	}
+	// The direct line costs more than the path via 3, the path via 2 costs more again.
+	assert.Equal(t, []rule{r(1, 3), r(3, 13)}, graphRules(g.CheapestPaths(class(1), class(13), cost)))
+	// Equal costs, all cheapest paths are included.
+	costs[r(1, 2)] = 1
+	assert.Equal(t, []rule{r(1, 2), r(1, 3), r(2, 13), r(3, 13)}, graphRules(g.CheapestPaths(class(1), class(13), cost)))
+	// Uniform cost is the same as ShortestPaths.
+	uniform := func(*Line) float64 { return 1 }
+	assert.Equal(t, graphRules(g.ShortestPaths(class(1), class(13))), graphRules(g.CheapestPaths(class(1), class(13), uniform)))
+}
+
+func TestGraph_BoundedPaths(t *testing.T) {
+	g := testGraph([]rule{r(1, 2), r(2, 3), r(3, 13), r(1, 3), r(1, 13), r(2, 11)})
+	for _, x := range []struct {
+		name string
+		opts PathOptions
+		want []rule
+	}{
+		{"unbounded", PathOptions{}, []rule{r(1, 2), r(1, 3), r(1, 13), r(2, 3), r(3, 13)}},
+		{"length", PathOptions{MaxLength: 2}, []rule{r(1, 3), r(1, 13), r(3, 13)}},
+		{"count", PathOptions{MaxCount: 1}, []rule{r(1, 13)}},
+		{"count and length", PathOptions{MaxCount: 2, MaxLength: 3}, []rule{r(1, 3), r(1, 13), r(3, 13)}},
+	} {
+		t.Run(x.name, func(t *testing.T) {
+			assert.Equal(t, x.want, graphRules(g.BoundedPaths(class(1), class(13), x.opts)))
+		})
+	}
+}
+
+func TestGraph_KShortestPaths(t *testing.T) {
+	g := testGraph([]rule{r(1, 2), r(2, 13), r(1, 3), r(3, 13), r(1, 13), r(1, 4), r(4, 5), r(5, 13)})
+	costs := map[rule]float64{r(1, 13): 5}
+	cost := func(l *Line) float64 {
+		if c, ok := costs[l.Rule.(rule)]; ok {
+			return c
+		}
+		return 1
+	}
+	assert.Equal(t, []rule{r(1, 2), r(1, 3), r(2, 13), r(3, 13)}, graphRules(g.KShortestPaths(class(1), class(13), 2, cost)))
+	assert.Equal(t, []rule{r(1, 2), r(1, 3), r(1, 4), r(2, 13), r(3, 13), r(4, 5), r(5, 13)}, graphRules(g.KShortestPaths(class(1), class(13), 3, cost)))
+	assert.Len(t, graphRules(g.KShortestPaths(class(1), class(13), 10, cost)), 8)
+	assert.Empty(t, graphRules(g.KShortestPaths(class(13), class(1), 10, cost)))
+}
+
+// Path searches on a large, dense graph must be bounded.
+func TestGraph_Paths_Large(t *testing.T) {
+	var rules []rule
+	const n = 40
+	for i := class(0); i < n; i++ {
+		for j := class(0); j < n; j++ {
+			if i != j {
+				rules = append(rules, r(i, j))
+			}
+		}
+	}
+	g := testGraph(rules)
+	uniform := func(*Line) float64 { return 1 }
+	assert.NotEmpty(t, graphRules(g.BoundedPaths(class(0), class(n-1), PathOptions{MaxCount: 100})))
+	assert.Len(t, graphRules(g.BoundedPaths(class(0), class(n-1), PathOptions{MaxLength: 2})), 1+2*(n-2))
+	assert.Len(t, graphRules(g.KShortestPaths(class(0), class(n-1), 10, uniform)), 1+2*9)
+}
@@ -1,6 +1,8 @@
 package graph
 
 import (
+	"sync"
+
 	"github.com/korrel8r/korrel8r/pkg/korrel8r"
 	"github.com/korrel8r/korrel8r/pkg/unique"
 	"gonum.org/v1/gonum/graph"
@@ -11,41 +13,93 @@ import (
 
 // Traverse traverses rules on a path graph in topological order.
 //
-// Cyclic components are traversed in topological order, but rules within the cycle are traversed in arbitrary order.
+// Lines that start from the same node, and nodes that do not depend on each other, are traversed concurrently.
+// The traverse function must be safe for concurrent use.
+// All lines into a node are traversed before any line out of it, except for lines inside a cycle.
+//
+// Cyclic components are traversed in topological order, but rules within the cycle are traversed
+// sequentially in arbitrary order.
 func (g *Graph) Traverse(traverse func(l *Line)) error {
 	ordered, err := topo.Sort(g)
 	cycles, ok := err.(topo.Unorderable)
 	if err != nil && !ok {
 		return err
 	}
-	j := 0 // cycles index
-	for _, n := range ordered {
+	// Each component is a single node or a cycle, done is closed when the component is traversed.
+	components := make([][]graph.Node, len(ordered))
+	done := make([]chan struct{}, len(ordered))
+	componentOf := map[int64]int{} // Node ID to component index.
+	j := 0                         // cycles index
+	for i, n := range ordered {
 		if n != nil {
-			g.traverseFrom(n, traverse)
+			components[i] = []graph.Node{n}
 		} else {
-			inside := make(unique.Set[int64], len(cycles[j]))
-			for _, n := range cycles[j] {
-				inside.Add(n.ID())
-			}
-			for _, n := range cycles[j] {
-				// Visit lines inside the cycle before those that leave it.
-				g.traverseFrom(n, func(l *Line) {
-					if inside.Has(l.To().ID()) {
-						traverse(l)
-					}
-				})
-				g.traverseFrom(n, func(l *Line) {
-					if !inside.Has(l.To().ID()) {
-						traverse(l)
-					}
-				})
-			}
+			components[i] = cycles[j]
 			j++
 		}
+		for _, n := range components[i] {
+			componentOf[n.ID()] = i
+		}
+		done[i] = make(chan struct{})
 	}
+	var wg sync.WaitGroup
+	for i := range components {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer close(done[i])
+			// Wait for all components with lines into this component.
+			for _, n := range components[i] {
+				from := g.To(n.ID())
+				for from.Next() {
+					if k := componentOf[from.Node().ID()]; k != i {
+						<-done[k]
+					}
+				}
+			}
+			if len(components[i]) == 1 && ordered[i] != nil {
+				g.traverseFromConcurrent(components[i][0], traverse)
+			} else {
+				g.traverseCycle(components[i], traverse)
+			}
+		}(i)
+	}
+	wg.Wait()
 	return nil
 }
 
+// traverseCycle traverses lines of a cycle sequentially, lines inside the cycle before those that leave it.
+func (g *Graph) traverseCycle(cycle []graph.Node, traverse func(l *Line)) {
+	inside := make(unique.Set[int64], len(cycle))
+	for _, n := range cycle {
+		inside.Add(n.ID())
+	}
+	for _, n := range cycle {
+		g.traverseFrom(n, func(l *Line) {
+			if inside.Has(l.To().ID()) {
+				traverse(l)
+			}
+		})
+	}
+	for _, n := range cycle {
+		g.traverseFrom(n, func(l *Line) {
+			if !inside.Has(l.To().ID()) {
+				traverse(l)
+			}
+		})
+	}
+}
+
+// traverseFromConcurrent traverses each line from node concurrently, and waits for all to finish.
+func (g *Graph) traverseFromConcurrent(node graph.Node, traverse func(l *Line)) {
+	var wg sync.WaitGroup
+	g.traverseFrom(node, func(l *Line) {
+		wg.Add(1)
+		go func() { defer wg.Done(); traverse(l) }()
+	})
+	wg.Wait()
+}
+
 // Neighbours creates a neighbourhood graph around start and traverses rules breadth-first.
 // If traverse == nil, just create the neighbourhood graph.
 func (g *Graph) Neighbours(start korrel8r.Class, depth int, travers func(l *Line)) *Graph {
@@ -63,6 +117,32 @@ func (g *Graph) Neighbours(start korrel8r.Class, depth int, travers func(l *Line
 	return sub
 }
 
+// ReverseNeighbours creates a graph of the lines on paths of length <= depth that end at goal.
+// It walks lines backwards breadth-first, the graph contains classes that may lead to goal.
+func (g *Graph) ReverseNeighbours(goal korrel8r.Class, depth int) *Graph {
+	sub := g.Data.EmptyGraph()
+	goalNode := g.NodeFor(goal)
+	seen := unique.Set[int64]{}
+	seen.Add(goalNode.ID())
+	frontier := []*Node{goalNode}
+	for d := 0; d < depth && len(frontier) > 0; d++ {
+		var next []*Node
+		for _, n := range frontier {
+			g.EachLineTo(n, func(l *Line) {
+				sub.SetLine(l)
+				if from := l.From().(*Node); seen.Add(from.ID()) {
+					next = append(next, from)
+				}
+			})
+		}
+		frontier = next
+	}
+	if sub.Node(goalNode.ID()) == nil {
+		sub.AddNode(goalNode)
+	}
+	return sub
+}
+
 // traverseFrom traverses each edge from node, returns true if any edge returns true.
 func (g *Graph) traverseFrom(node graph.Node, traverse func(l *Line)) {
 	goals := g.From(node.ID())
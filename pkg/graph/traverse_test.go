@@ -2,6 +2,7 @@ package graph
 
 import (
 	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -31,14 +32,57 @@ func TestTraverse(t *testing.T) {
 	} {
 		t.Run(x.name, func(t *testing.T) {
 			g := testGraph(x.graph)
-			var got []rule
-			err := g.Traverse(func(l *Line) { got = append(got, RuleFor(l).(rule)) })
+			var (
+				got []rule
+				mu  sync.Mutex
+			)
+			err := g.Traverse(func(l *Line) {
+				mu.Lock()
+				defer mu.Unlock()
+				got = append(got, RuleFor(l).(rule))
+			})
 			assert.NoError(t, err)
 			assertComponentOrder(t, x.want, got)
 		})
 	}
 }
 
+func TestTraverse_Concurrent(t *testing.T) {
+	// 11 and 12 are independent, they should be traversed concurrently.
+	g := testGraph([]rule{{1, 11}, {1, 12}, {11, 21}, {12, 22}})
+	var (
+		mu      sync.Mutex
+		active  int
+		maxSeen int
+	)
+	entered, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- g.Traverse(func(l *Line) {
+			mu.Lock()
+			active++
+			if active > maxSeen {
+				maxSeen = active
+			}
+			mu.Unlock()
+			entered <- struct{}{}
+			<-release
+			mu.Lock()
+			active--
+			mu.Unlock()
+		})
+	}()
+	// Both lines of each stage must be in progress before either is released.
+	for stage := 0; stage < 2; stage++ {
+		<-entered
+		<-entered
+		release <- struct{}{}
+		release <- struct{}{}
+	}
+	assert.NoError(t, <-done)
+	assert.Equal(t, 2, maxSeen)
+}
+
 func TestNeighbours(t *testing.T) {
 	g := testGraph([]rule{{1, 11}, {1, 12}, {1, 13}, {11, 22}, {12, 22}, {12, 13}, {22, 99}})
 	for _, x := range []struct {
@@ -65,3 +109,23 @@ func TestNeighbours(t *testing.T) {
 		})
 	}
 }
+
+func TestReverseNeighbours(t *testing.T) {
+	g := testGraph([]rule{{1, 11}, {1, 12}, {1, 13}, {11, 22}, {12, 22}, {12, 13}, {22, 99}, {99, 1}})
+	for _, x := range []struct {
+		depth int
+		want  []rule
+	}{
+		{depth: 0, want: nil},
+		{depth: 1, want: []rule{{11, 22}, {12, 22}}},
+		{depth: 2, want: []rule{{1, 11}, {1, 12}, {11, 22}, {12, 22}}},
+		{depth: 3, want: []rule{{1, 11}, {1, 12}, {11, 22}, {12, 22}, {99, 1}}},
+		{depth: 4, want: []rule{{1, 11}, {1, 12}, {11, 22}, {12, 22}, {22, 99}, {99, 1}}},
+	} {
+		t.Run(fmt.Sprintf("depth=%v", x.depth), func(t *testing.T) {
+			sub := g.ReverseNeighbours(class(22), x.depth)
+			assert.Equal(t, x.want, graphRules(sub))
+			assert.NotNil(t, sub.Node(g.NodeFor(class(22)).ID()), "goal is always included")
+		})
+	}
+}
@@ -6,7 +6,9 @@ package korrel8r
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
 	"time"
 
 	"sigs.k8s.io/yaml"
@@ -59,6 +61,43 @@ type IDer interface {
 	ID(Object) any // Comparable ID for de-duplication.
 }
 
+// ObjectID returns an identifier for an object of class c: c.ID(o) if c is an IDer, the JSON string of o otherwise.
+func ObjectID(c Class, o Object) any {
+	if id, ok := c.(IDer); ok {
+		return id.ID(o)
+	}
+	return JSONString(o)
+}
+
+// Newer is implemented by classes that can create an empty object.
+// Objects of a Newer class can be decoded from JSON, see UnmarshalObject.
+type Newer interface {
+	New() Object // New empty object of this class.
+}
+
+// UnmarshalObject decodes a JSON object of class c.
+// If c is not a Newer the object is decoded as a generic JSON value.
+func UnmarshalObject(c Class, b []byte) (Object, error) {
+	var o Object
+	if n, ok := c.(Newer); ok {
+		o = n.New()
+	}
+	if o == nil {
+		err := json.Unmarshal(b, &o)
+		return o, err
+	}
+	v := reflect.ValueOf(o)
+	if v.Kind() == reflect.Pointer {
+		err := json.Unmarshal(b, o)
+		return o, err
+	}
+	// Decode into a pointer to a copy of the value.
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	err := json.Unmarshal(b, p.Interface())
+	return p.Elem().Interface(), err
+}
+
 // ClassName returns the fully qualified domain/name of a class, e.g. "k8s/Pod.v1."
 func ClassName(c Class) string {
 	if c == nil {
@@ -81,7 +120,8 @@ type Store interface {
 
 	// Get requests objects selected by the Query.
 	// Collected objects are appended to the Appender.
-	Get(context.Context, Query, Appender) error
+	// Constraint may be nil, if not the store should return only objects that satisfy it.
+	Get(context.Context, Query, *Constraint, Appender) error
 }
 
 // Constraint included in a reference to restrict the resulting objects.
@@ -91,6 +131,54 @@ type Constraint struct {
 	End   *time.Time `json:"end,omitempty"`   // Include only results timestamped before this time.
 }
 
+// CompareTime returns -1 if t is before the constraint interval, +1 if after, 0 if inside.
+// A nil Constraint or missing Start/End places no restriction.
+func (c *Constraint) CompareTime(t time.Time) int {
+	switch {
+	case c == nil:
+		return 0
+	case c.Start != nil && t.Before(*c.Start):
+		return -1
+	case c.End != nil && t.After(*c.End):
+		return +1
+	}
+	return 0
+}
+
+// Combine returns the intersection of two constraints: the later start, the earlier end and the smaller limit.
+// A nil constraint places no restriction, combining with nil returns the other constraint.
+// Neither constraint is modified.
+// If the time windows do not overlap, the combined start is after the end, see Empty.
+func (c *Constraint) Combine(other *Constraint) *Constraint {
+	if c == nil {
+		return other
+	}
+	if other == nil {
+		return c
+	}
+	combined := *c
+	if other.Limit != nil && (c.Limit == nil || *other.Limit < *c.Limit) {
+		combined.Limit = other.Limit
+	}
+	if other.Start != nil && (c.Start == nil || other.Start.After(*c.Start)) {
+		combined.Start = other.Start
+	}
+	if other.End != nil && (c.End == nil || other.End.Before(*c.End)) {
+		combined.End = other.End
+	}
+	return &combined
+}
+
+// Empty returns true if the constraint start is after its end, no timestamp can satisfy it.
+func (c *Constraint) Empty() bool {
+	return c != nil && c.Start != nil && c.End != nil && c.Start.After(*c.End)
+}
+
+// LimitReached returns true if n objects is at or above the constraint limit.
+func (c *Constraint) LimitReached(n int) bool {
+	return c != nil && c.Limit != nil && uint(n) >= *c.Limit
+}
+
 // Appender gathers results from Store.Get calls. See also Result.
 type Appender interface{ Append(...Object) }
 
@@ -100,14 +188,20 @@ type Rule interface {
 	// Apply the rule to a start Object, return a Query for results.
 	// Optional Constraint may be included in the Query.
 	Apply(start Object, constraint *Constraint) (Query, error)
-	// Class of start object. If nil, this is a "wildcard" rule that can start from any class it applies to.
+	// Class of start object, must not be nil.
+	// A "wildcard" rule that applies to several classes is represented by a Rule for each class, see templaterule.
 	Start() Class
 	// Class of desired result object(s), must not be nil.
+	// If the start object leads to a different class, Apply returns ErrWrongGoal.
 	Goal() Class
 	// Name of the rule
 	String() string
 }
 
+// ErrWrongGoal is returned by Rule.Apply if the start object leads to a class other than the rule's goal.
+// Rules with a wildcard goal choose the goal class from the start object, the rules for other goal classes do not apply.
+var ErrWrongGoal = errors.New("wrong goal")
+
 // RuleName returns a string including the rule name with full start and goal class names.
 func RuleName(r Rule) string {
 	return fmt.Sprintf("%v [%v]->[%v]", r, ClassName(r.Start()), ClassName(r.Goal()))
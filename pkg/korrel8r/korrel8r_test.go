@@ -0,0 +1,32 @@
+package korrel8r
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstraint_Combine(t *testing.T) {
+	t1, t2, t3, t4 := time.Unix(1, 0), time.Unix(2, 0), time.Unix(3, 0), time.Unix(4, 0)
+	l1, l2 := uint(1), uint(2)
+	a := &Constraint{Start: &t1, End: &t3, Limit: &l2}
+	b := &Constraint{Start: &t2, End: &t4, Limit: &l1}
+	assert.Equal(t, &Constraint{Start: &t2, End: &t3, Limit: &l1}, a.Combine(b))
+	assert.Equal(t, a.Combine(b), b.Combine(a))
+	assert.Equal(t, &Constraint{Start: &t1, End: &t3, Limit: &l2}, a, "unmodified")
+
+	c := &Constraint{End: &t2}
+	assert.Equal(t, &Constraint{Start: &t1, End: &t2, Limit: &l2}, a.Combine(c))
+	assert.Same(t, a, a.Combine(nil))
+	assert.Same(t, a, (*Constraint)(nil).Combine(a))
+	assert.Nil(t, (*Constraint)(nil).Combine(nil))
+
+	// Windows that do not overlap.
+	assert.False(t, a.Combine(b).Empty())
+	d := &Constraint{Start: &t4}
+	assert.True(t, a.Combine(d).Empty())
+	assert.True(t, d.Combine(a).Empty())
+	assert.False(t, d.Empty())
+	assert.False(t, (*Constraint)(nil).Empty())
+}
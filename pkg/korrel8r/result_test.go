@@ -0,0 +1,18 @@
+package korrel8r
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type idFunc func(Object) any
+
+func (f idFunc) ID(o Object) any { return f(o) }
+
+func TestSetResult_Append(t *testing.T) {
+	r := NewSetResult(idFunc(func(o Object) any { return o }))
+	r.Append(1, 2, 1)
+	r.Append(2, 3)
+	assert.Equal(t, []Object{1, 2, 3}, r.List())
+}
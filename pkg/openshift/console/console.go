@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/korrel8r/korrel8r/internal/pkg/logging"
+	"github.com/korrel8r/korrel8r/pkg/domains"
 	"github.com/korrel8r/korrel8r/pkg/engine"
 	"github.com/korrel8r/korrel8r/pkg/korrel8r"
 )
@@ -47,18 +48,15 @@ func (c *Console) ConsoleURLToQuery(u *url.URL) (q korrel8r.Query, err error) {
 			log.V(2).Error(err, "console to query", "url", u)
 		}
 	}()
-	for _, x := range []struct{ prefix, domain string }{
-		{"/k8s", "k8s"},
-		{"/search", "k8s"},
-		{"/monitoring/alerts", "alert"},
-		{"/monitoring/logs", "logs"},
-		{"/monitoring/query-browser", "metric"},
-	} {
-		if strings.HasPrefix(path.Join("/", u.Path), x.prefix) {
-			if qc := c.converter(x.domain); qc != nil {
-				return qc.ConsoleURLToQuery(u)
+	// Console path prefixes are registered by domain plugins.
+	for _, p := range domains.List() {
+		for _, prefix := range p.ConsolePaths {
+			if strings.HasPrefix(path.Join("/", u.Path), prefix) {
+				if qc := c.converter(p.Domain.String()); qc != nil {
+					return qc.ConsoleURLToQuery(u)
+				}
+				return nil, fmt.Errorf("cannot convert console URL to query: %v", u)
 			}
-			break
 		}
 	}
 	return nil, fmt.Errorf("cannot convert console URL to query: %v", u)
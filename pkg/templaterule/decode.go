@@ -1,11 +1,19 @@
 package templaterule
 
 import (
+	"errors"
+	"fmt"
 	"io"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/korrel8r/korrel8r/internal/pkg/logging"
 	"github.com/korrel8r/korrel8r/pkg/engine"
-	"k8s.io/apimachinery/pkg/util/yaml"
+	"go.uber.org/multierr"
+	"golang.org/x/exp/slices"
+	yaml3 "gopkg.in/yaml.v3"
+	"sigs.k8s.io/yaml"
 )
 
 var log = logging.Log()
@@ -40,25 +48,299 @@ func (g Groups) Expand(names []string) []string {
 type RuleFile struct {
 	Groups []Group
 	Rules  []Rule
+	Tests  []Test // Tests for rules, see Test. Ignored by Decode.
 }
 
 // Decode template rules and add them to an engine.
-func Decode(r io.Reader, e *engine.Engine) error {
-	d := yaml.NewYAMLOrJSONDecoder(r, 1024)
-	var rf RuleFile
-	if err := d.Decode(&rf); err != nil {
+// It is the same as DecodeFile with an empty file name.
+func Decode(r io.Reader, e *engine.Engine) error { return DecodeFile("", r, e) }
+
+// DecodeFile decodes template rules from the named file and adds them to an engine.
+//
+// DecodeFile does not stop at the first bad rule, valid rules are added to the engine.
+// The returned error combines all the errors in the file, see multierr.Errors.
+// Each one is an *Error with the file name and the position of the rule or field that caused it.
+func DecodeFile(name string, r io.Reader, e *engine.Engine) error {
+	rf, err := decodeFile(name, r)
+	if err != nil {
+		return err
+	}
+	for i, tr := range rf.Rules {
+		if err := addRule(tr, rf.groups, e); err != nil {
+			rf.errs = append(rf.errs, rf.ruleError(i, err))
+		}
+	}
+	return rf.err()
+}
+
+// GroupProblem is reported by Lint for a group that expands to classes that are not in the domain of a rule.
+const GroupProblem engine.ProblemKind = "group"
+
+// Lint is the same as LintFile with an empty file name.
+func Lint(r io.Reader, e *engine.Engine) (engine.Problems, error) { return LintFile("", r, e) }
+
+// LintFile is like DecodeFile, but reports rules that use groups with unknown classes as problems instead of failing.
+// Rules with problems are not added to the engine. Other errors are returned as for DecodeFile.
+func LintFile(name string, r io.Reader, e *engine.Engine) (engine.Problems, error) {
+	rf, err := decodeFile(name, r)
+	if err != nil {
+		return nil, err
+	}
+	var problems engine.Problems
+	for i, tr := range rf.Rules {
+		ps := lintGroups(tr, rf.groups, e)
+		if len(ps) == 0 {
+			if err := addRule(tr, rf.groups, e); err != nil {
+				rf.errs = append(rf.errs, rf.ruleError(i, err))
+			}
+		}
+		problems = append(problems, ps...)
+	}
+	return problems, rf.err()
+}
+
+// Error is an error in a rule file, with the position of the rule or field that caused it.
+type Error struct {
+	File         string // File name, empty if not known.
+	Line, Column int    // Position in the file, 0 if not known.
+	Err          error
+}
+
+func (e *Error) Error() string {
+	var pos []string
+	if e.File != "" {
+		pos = append(pos, e.File)
+	}
+	if e.Line > 0 {
+		pos = append(pos, strconv.Itoa(e.Line))
+		if e.Column > 0 {
+			pos = append(pos, strconv.Itoa(e.Column))
+		}
+	}
+	if len(pos) == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%v: %v", strings.Join(pos, ":"), e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// ruleFile is a decoded RuleFile, with YAML nodes to locate errors.
+type ruleFile struct {
+	RuleFile
+	name     string
+	lines    []string // Source lines
+	groups   Groups
+	nodes    []*yaml3.Node // nodes[i] is the node for Rules[i]
+	errs     []*Error
+	testErrs []*Error // Errors in the tests section, ignored by Decode.
+}
+
+// decodeFile decodes groups, rules and tests. Rules and tests that cannot be decoded are skipped, and recorded in errs.
+// Returns an error if the file is not valid YAML or JSON.
+func decodeFile(name string, r io.Reader) (*ruleFile, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, &Error{File: name, Err: err}
+	}
+	var doc yaml3.Node
+	if err := yaml3.Unmarshal(b, &doc); err != nil {
+		err := syntaxError(err)
+		err.File = name
+		return nil, err
+	}
+	rf := &ruleFile{name: name, lines: strings.Split(string(b), "\n")}
+	if len(doc.Content) > 0 {
+		root := doc.Content[0]
+		if root.Kind != yaml3.MappingNode {
+			err := nodeError(root, errors.New("rule file must be a map"))
+			err.File = name
+			return nil, err
+		}
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			key, value := root.Content[i].Value, root.Content[i+1]
+			switch {
+			case strings.EqualFold(key, "groups"):
+				if err := decodeNode(value, &rf.Groups); err != nil {
+					rf.errs = append(rf.errs, nodeError(value, fmt.Errorf("groups: %w", err)))
+				}
+			case strings.EqualFold(key, "rules"):
+				rf.decodeRules(value)
+			case strings.EqualFold(key, "tests"):
+				rf.decodeTests(value)
+			}
+		}
+	}
+	rf.groups = NewGroups(rf.Groups)
+	return rf, nil
+}
+
+func (rf *ruleFile) decodeRules(n *yaml3.Node) {
+	if n.Kind != yaml3.SequenceNode {
+		rf.errs = append(rf.errs, nodeError(n, errors.New("rules: must be a list")))
+		return
+	}
+	for i, rn := range n.Content {
+		var tr Rule
+		if err := decodeNode(rn, &tr); err != nil {
+			name := fmt.Sprintf("rules[%v]", i)
+			if nn := fieldNode(rn, "name"); nn != nil && nn.Value != "" {
+				name = nn.Value
+			}
+			rf.errs = append(rf.errs, nodeError(rn, fmt.Errorf("rule %v: %w", name, err)))
+			continue
+		}
+		rf.Rules = append(rf.Rules, tr)
+		rf.nodes = append(rf.nodes, rn)
+	}
+}
+
+func (rf *ruleFile) decodeTests(n *yaml3.Node) {
+	if n.Kind != yaml3.SequenceNode {
+		rf.testErrs = append(rf.testErrs, nodeError(n, errors.New("tests: must be a list")))
+		return
+	}
+	for i, tn := range n.Content {
+		var t Test
+		if err := decodeNode(tn, &t); err != nil {
+			name := fmt.Sprintf("tests[%v]", i)
+			if nn := fieldNode(tn, "name"); nn != nil && nn.Value != "" {
+				name = nn.Value
+			}
+			rf.testErrs = append(rf.testErrs, nodeError(tn, fmt.Errorf("test %v: %w", name, err)))
+			continue
+		}
+		rf.Tests = append(rf.Tests, t)
+	}
+}
+
+// ruleError returns an error located at Rules[i], or at the field of the rule that caused err.
+// Template parse errors are located at the start of the line in the template, they have no column.
+func (rf *ruleFile) ruleError(i int, err error) *Error {
+	n := rf.nodes[i]
+	var fe *fieldError
+	if errors.As(err, &fe) {
+		if fn := fieldNode(n, strings.Split(fe.field, ".")...); fn != nil {
+			n = fn
+		}
+	}
+	re := nodeError(n, err)
+	if m := templateErrorRE.FindStringSubmatch(err.Error()); m != nil && n.Kind == yaml3.ScalarNode {
+		line, _ := strconv.Atoi(m[1])
+		if n.Style&(yaml3.LiteralStyle|yaml3.FoldedStyle) != 0 {
+			re.Line, re.Column = n.Line+line, rf.indent(n.Line+line) // Block text starts after the indicator line.
+		} else if line > 1 {
+			re.Line, re.Column = n.Line+line-1, rf.indent(n.Line+line-1)
+		}
+	}
+	return re
+}
+
+var templateErrorRE = regexp.MustCompile(`template: [^:]*:([0-9]+):`)
+
+// indent returns the column of the first non-blank character on a source line, 0 if there is none.
+func (rf *ruleFile) indent(line int) int {
+	if line < 1 || line > len(rf.lines) {
+		return 0
+	}
+	s := rf.lines[line-1]
+	if i := strings.IndexFunc(s, func(r rune) bool { return r != ' ' && r != '\t' }); i >= 0 {
+		return i + 1
+	}
+	return 0
+}
+
+// err combines the rule errors in order of position, returns nil if there are none.
+func (rf *ruleFile) err() error { return rf.combine(rf.errs) }
+
+// combine sets the file name of errs, and combines them in order of position.
+func (rf *ruleFile) combine(errs []*Error) (err error) {
+	slices.SortStableFunc(errs, func(a, b *Error) bool {
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	for _, e := range errs {
+		e.File = rf.name
+		err = multierr.Append(err, e)
+	}
+	return err
+}
+
+// decodeNode decodes n into v using JSON field names, like the rest of the rule file.
+func decodeNode(n *yaml3.Node, v any) error {
+	b, err := yaml3.Marshal(n)
+	if err != nil {
 		return err
 	}
-	groups := NewGroups(rf.Groups)
-	for _, tr := range rf.Rules {
-		tr.Start.Classes = groups.Expand(tr.Start.Classes)
-		tr.Goal.Classes = groups.Expand(tr.Goal.Classes)
-		krs, err := tr.Rules(e)
-		if err != nil {
-			return err
+	return yaml.Unmarshal(b, v)
+}
+
+// fieldNode returns the value node at path in a tree of YAML maps, or nil if there is none.
+// Keys are matched without case, as for JSON field names.
+func fieldNode(n *yaml3.Node, path ...string) *yaml3.Node {
+	for _, key := range path {
+		var next *yaml3.Node
+		for i := 0; n.Kind == yaml3.MappingNode && i+1 < len(n.Content); i += 2 {
+			if strings.EqualFold(n.Content[i].Value, key) {
+				next = n.Content[i+1]
+				break
+			}
 		}
-		log.V(3).Info("adding template rules", "template", tr.Name, "expanded", len(krs))
-		e.AddRules(krs...)
+		if next == nil {
+			return nil
+		}
+		n = next
+	}
+	return n
+}
+
+func nodeError(n *yaml3.Node, err error) *Error {
+	return &Error{Line: n.Line, Column: n.Column, Err: err}
+}
+
+var syntaxErrorRE = regexp.MustCompile(`^yaml: line ([0-9]+): (.*)$`)
+
+// syntaxError converts a YAML syntax error, extracting the line number if there is one.
+func syntaxError(err error) *Error {
+	if m := syntaxErrorRE.FindStringSubmatch(err.Error()); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return &Error{Line: line, Err: errors.New(m[2])}
 	}
+	return &Error{Err: err}
+}
+
+func addRule(tr Rule, groups Groups, e *engine.Engine) error {
+	tr.Start.Classes = groups.Expand(tr.Start.Classes)
+	tr.Goal.Classes = groups.Expand(tr.Goal.Classes)
+	krs, err := tr.Rules(e)
+	if err != nil {
+		return err
+	}
+	log.V(3).Info("adding template rules", "template", tr.Name, "expanded", len(krs))
+	e.AddRules(krs...)
 	return nil
 }
+
+// lintGroups checks that groups used by a rule expand to classes in the rule's domains.
+func lintGroups(tr Rule, groups Groups, e *engine.Engine) (problems engine.Problems) {
+	for _, spec := range []ClassSpec{tr.Start, tr.Goal} {
+		domain := e.Domain(spec.Domain)
+		if domain == nil {
+			continue // Reported by Rules()
+		}
+		for _, name := range spec.Classes {
+			for _, class := range groups[name] {
+				if domain.Class(class) == nil {
+					problems = append(problems, engine.Problem{
+						Kind: GroupProblem,
+						Rule: tr.Name,
+						Msg:  fmt.Sprintf("group %v has unknown class %v in domain %v", name, class, domain),
+					})
+				}
+			}
+		}
+	}
+	return problems
+}
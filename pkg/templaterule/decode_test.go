@@ -1,6 +1,7 @@
 package templaterule
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 
@@ -8,6 +9,7 @@ import (
 	"github.com/korrel8r/korrel8r/pkg/engine"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/multierr"
 )
 
 func TestDecode(t *testing.T) {
@@ -55,3 +57,98 @@ func TestExpand(t *testing.T) {
 	want = []string{"f1", "f2", "b0", "m1"}
 	assert.Equal(t, want, got)
 }
+
+func TestLint(t *testing.T) {
+	e := engine.New()
+	foo := mock.Domain("foo a z")
+	e.AddDomain(foo, nil)
+	r := strings.NewReader(`
+groups:
+  - name: good
+    classes: [a]
+  - name: bad
+    classes: [a, nosuchclass]
+rules:
+  - name:   one
+    start:  {domain: "foo", classes: [good]}
+    goal:   {domain: "foo", classes: [z]}
+    result: {query: dummy}
+  - name:   two
+    start:  {domain: "foo", classes: [bad]}
+    goal:   {domain: "foo", classes: [z]}
+    result: {query: dummy}
+`)
+	problems, err := Lint(r, e)
+	require.NoError(t, err)
+	assert.Equal(t, engine.Problems{{Kind: GroupProblem, Rule: "two", Msg: "group bad has unknown class nosuchclass in domain foo"}}, problems)
+	want := []mock.Rule{mockRule("one", foo.Class("a"), foo.Class("z"))}
+	assert.Equal(t, want, mockRules(e.Rules()...))
+}
+
+func TestDecode_Errors(t *testing.T) {
+	e := engine.New()
+	e.AddDomain(mock.Domain("foo a z"), nil)
+	r := strings.NewReader(`rules:
+  - name:   good
+    start:  {domain: "foo", classes: [a]}
+    goal:   {domain: "foo", classes: [z]}
+    result: {query: dummy}
+  - name:   badclass
+    start:  {domain: "foo", classes: [nosuchclass]}
+    goal:   {domain: "foo", classes: [z]}
+    result: {query: dummy}
+  - name:   badtemplate
+    start:  {domain: "foo", classes: [a]}
+    goal:   {domain: "foo", classes: [z]}
+    result:
+      query: |-
+        "ok"
+        {{nosuchfunc}}
+  - name:   badcost
+    start:  {domain: "foo", classes: [a]}
+    goal:   {domain: "foo", classes: [z]}
+    cost:   expensive
+    result: {query: dummy}
+`)
+	err := Decode(r, e)
+	var got []string
+	for _, err := range multierr.Errors(err) {
+		var re *Error
+		require.ErrorAs(t, err, &re)
+		got = append(got, fmt.Sprintf("%v:%v", re.Line, re.Column))
+	}
+	assert.Equal(t, []string{"7:13", "16:9", "17:5"}, got)
+	assert.Contains(t, err.Error(), "7:13: rule badclass: start: unknown class nosuchclass in domain foo")
+	assert.Contains(t, err.Error(), "16:9: rule badtemplate: result.query: template:")
+	assert.Contains(t, err.Error(), "17:5: rule badcost:")
+	// Valid rules are added.
+	assert.Equal(t, []string{"good"}, func() (names []string) {
+		for _, r := range e.Rules() {
+			names = append(names, r.String())
+		}
+		return names
+	}())
+
+	err = DecodeFile("foo.yaml", strings.NewReader("rules:\n  - name: x\n    start: a: b\n"), e)
+	require.IsType(t, &Error{}, err)
+	assert.EqualError(t, err, "foo.yaml:3: mapping values are not allowed in this context")
+
+	err = DecodeFile("foo.yaml", strings.NewReader("rules:\n  - name: x\n    cost: expensive\n"), e)
+	assert.ErrorContains(t, err, "foo.yaml:2:5: rule x:")
+}
+
+func TestDecodeTests_Errors(t *testing.T) {
+	tests, err := DecodeTestsFile("foo.yaml", strings.NewReader(`
+rules:
+  - name: ignored
+    cost: expensive
+tests:
+  - {name: good, rule: r, object: {}}
+  - name: bad
+    rule: [r]
+`))
+	assert.Len(t, tests, 1)
+	assert.Equal(t, "good", tests[0].Name)
+	require.IsType(t, &Error{}, err)
+	assert.ErrorContains(t, err, "foo.yaml:7:5: test bad:")
+}
@@ -7,6 +7,7 @@ import (
 	"reflect"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/korrel8r/korrel8r/pkg/korrel8r"
 )
@@ -36,6 +37,8 @@ import (
 //	mkmap
 //	  Returns a map[any]any formed from (key, value, key2, value2...) argument pairs.
 //	  Useful for passing parameters to a nested template.
+//	duration
+//	  Parses a time.Duration string like "-10m", for example: {{.ActiveAt.Add (duration "-10m")}}
 var Funcs map[string]any
 
 func init() {
@@ -51,6 +54,7 @@ func init() {
 		"mkslice":     mkslice,
 		"mkmap":       mkmap,
 		"tolower":     strings.ToLower,
+		"duration":    time.ParseDuration,
 	}
 }
 
@@ -1,45 +1,160 @@
 package templaterule
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
 	"text/template"
 
-	"bytes"
-
+	"github.com/korrel8r/korrel8r/pkg/engine"
 	"github.com/korrel8r/korrel8r/pkg/korrel8r"
 )
 
-var _ korrel8r.Rule = &rule{}
+var (
+	_ korrel8r.Rule       = &rule{}
+	_ engine.Coster       = &rule{}
+	_ engine.Templater    = &rule{}
+	_ engine.Constrainer  = &rule{}
+	_ engine.MultiApplier = &rule{}
+)
 
 // rule implements korrel8r.Rule
 type rule struct {
-	query, constraint *template.Template
+	query, constraint *ruleTemplate
 	start, goal       korrel8r.Class
+	cost              float64
 }
 
 func (r *rule) String() string        { return r.query.Name() }
 func (r *rule) Start() korrel8r.Class { return r.start }
 func (r *rule) Goal() korrel8r.Class  { return r.goal }
+func (r *rule) Cost() float64         { return r.cost }
+
+// Template returns the query and constraint templates.
+func (r *rule) Template() string {
+	s := r.query.Root.String()
+	if r.constraint != nil && r.constraint.Tree != nil {
+		s += "\n" + r.constraint.Root.String()
+	}
+	return s
+}
+
+// Apply the rule by applying the template, the template must generate a single query.
+// See ApplyAll.
+func (r *rule) Apply(start korrel8r.Object, c *korrel8r.Constraint) (korrel8r.Query, error) {
+	queries, err := r.ApplyAll(start, c)
+	if err != nil {
+		return nil, err
+	}
+	if len(queries) != 1 {
+		return nil, fmt.Errorf("apply: expected one query, got %v", len(queries))
+	}
+	return queries[0], nil
+}
+
+// errBlankQuery is returned by ApplyAll if the template generates no queries, the rule does not apply.
+var errBlankQuery = errors.New("blank query")
 
-// Apply the rule by applying the template.
+// separator splits template output into query documents.
+var separator = regexp.MustCompile(`(?m)^---[ \t]*$`)
+
+// ApplyAll applies the template, and returns a query for each document in the output.
+// Documents are separated by lines containing only "---", blank documents are ignored.
 // The template will be executed with start as the "." context object.
 // A function "constraint" returns the constraint.
-func (r *rule) Apply(start korrel8r.Object, c *korrel8r.Constraint) (korrel8r.Query, error) {
+//
+// Queries for a class other than the rule goal are ignored, if there are only such queries
+// ApplyAll returns korrel8r.ErrWrongGoal.
+func (r *rule) ApplyAll(start korrel8r.Object, c *korrel8r.Constraint) ([]korrel8r.Query, error) {
 	b := &bytes.Buffer{}
-
-	err := r.query.Funcs(map[string]any{"constraint": func() *korrel8r.Constraint { return c }}).Execute(b, start)
-	if err != nil {
+	if err := r.query.execute(b, start, c); err != nil {
 		return nil, fmt.Errorf("apply: %s", err)
 	}
 
-	q, err := r.Goal().Domain().UnmarshalQuery(b.Bytes())
-	if err != nil {
-		return nil, fmt.Errorf("apply: unmarshal error: %w", err)
+	var (
+		queries []korrel8r.Query
+		wrong   []string
+	)
+	for _, doc := range separator.Split(b.String(), -1) {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+		q, err := r.Goal().Domain().UnmarshalQuery([]byte(doc))
+		if err != nil {
+			return nil, fmt.Errorf("apply: unmarshal error: %w", err)
+		}
+		if q.Class() != r.Goal() {
+			wrong = append(wrong, korrel8r.ClassName(q.Class()))
+			continue
+		}
+		queries = append(queries, q)
+	}
+	switch {
+	case len(queries) > 0:
+		return queries, nil
+	case len(wrong) > 0:
+		return nil, fmt.Errorf("apply: %w: %v", korrel8r.ErrWrongGoal, strings.Join(wrong, ", "))
+	default:
+		return nil, fmt.Errorf("apply: %w", errBlankQuery)
 	}
+}
 
-	if q.Class() != r.Goal() {
-		return nil, fmt.Errorf("apply: wrong goal: %v", korrel8r.ClassName(q.Class()))
+// Constraint executes the constraint template, if there is one, with start as the "." context object.
+// The resulting constraint is combined with c, see korrel8r.Constraint.Combine.
+// If the template yields a blank string, c is returned unchanged.
+func (r *rule) Constraint(start korrel8r.Object, c *korrel8r.Constraint) (*korrel8r.Constraint, error) {
+	if r.constraint == nil {
+		return c, nil
+	}
+	b := &bytes.Buffer{}
+	if err := r.constraint.execute(b, start, c); err != nil {
+		return nil, fmt.Errorf("constraint: %s", err)
+	}
+	if len(bytes.TrimSpace(b.Bytes())) == 0 {
+		return c, nil
 	}
+	var rc korrel8r.Constraint
+	if err := json.Unmarshal(b.Bytes(), &rc); err != nil {
+		return nil, fmt.Errorf("constraint: unmarshal error: %w", err)
+	}
+	return c.Combine(&rc), nil
+}
+
+// ruleTemplate is shared by all the rules generated from a template rule, rules may be applied concurrently.
+//
+// The "constraint" function must return the constraint for each execution, so it can't be bound to the shared template.
+// Executions use clones from a pool, each clone binds "constraint" once to a function that returns its current constraint.
+type ruleTemplate struct {
+	*template.Template
+	pool sync.Pool // *boundTemplate
+}
 
-	return q, nil
+// boundTemplate is a clone of a ruleTemplate, with a "constraint" function that returns constraint.
+type boundTemplate struct {
+	t          *template.Template
+	constraint *korrel8r.Constraint
+}
+
+// execute the template with start as the "." context object, and a "constraint" function that returns c.
+func (rt *ruleTemplate) execute(w io.Writer, start korrel8r.Object, c *korrel8r.Constraint) error {
+	bt, _ := rt.pool.Get().(*boundTemplate)
+	if bt == nil {
+		t, err := rt.Clone()
+		if err != nil {
+			return err
+		}
+		bt = &boundTemplate{}
+		bt.t = t.Funcs(map[string]any{"constraint": func() *korrel8r.Constraint { return bt.constraint }})
+	}
+	defer func() {
+		bt.constraint = nil
+		rt.pool.Put(bt)
+	}()
+	bt.constraint = c
+	return bt.t.Execute(w, start)
 }
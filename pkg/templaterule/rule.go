@@ -7,7 +7,13 @@ import (
 )
 
 // Rule is a template rule specification that can be serialized as JSON.
-// It generates one or more korrel8r.Rule.
+// It generates one or more korrel8r.Rule, one for each combination of start and goal classes.
+//
+// A rule with several goal classes is a "wildcard" rule, the query template can choose the goal
+// classes based on the start object, with one query document per class separated by "---".
+// Each generated rule uses the queries for its own goal class. Rules with no query for their goal class
+// do not apply, their Apply returns korrel8r.ErrWrongGoal. In a graph, the rule is a line to each possible goal class.
+// A rule with no start classes is a "wildcard" start, it generates a rule for each class in the start domain.
 type Rule struct {
 	// Name is a short, descriptive name.
 	// If omitted, a name is generated from Start and Goal.
@@ -19,6 +25,10 @@ type Rule struct {
 	// Goal specifies the set of classes that this rule can produce.
 	Goal ClassSpec `json:"goal"`
 
+	// Cost of following this rule, optional. Rules with a lower cost are preferred when
+	// following cheapest paths, the default cost is 1. See engine.Coster.
+	Cost float64 `json:"cost,omitempty"`
+
 	// Result contains templates to generate the result of applying this rule.
 	// Each template is applied to an object from one of the `start` classes.
 	// If any template yields a blank string or an error, the rule does not apply.
@@ -38,11 +48,15 @@ type ClassSpec struct {
 // ResultSpec contains result templates.
 type ResultSpec struct {
 	// Query template generates a query object suitable for the goal store.
+	// The template can generate several queries, separated by lines containing only "---".
+	// Each query is evaluated, duplicate queries are ignored. See engine.MultiApplier.
 	Query string `json:"query"`
 
 	// Constraint template is optional, it generates a korrel8r.Constraint in JSON form.
-	// This constraint is combined with the constraint already in force, if there is one.
-	// See Constraint.Combine
+	// The template is applied to the start object, like Query.
+	// This constraint is combined with the constraint already in force, if there is one,
+	// and passed to the goal store. The "constraint" function of the Query template returns the combined constraint.
+	// See korrel8r.Constraint.Combine and engine.Constrainer.
 	Constraint string `json:"constraint,omitempty"`
 }
 
@@ -1,6 +1,7 @@
 package templaterule
 
 import (
+	"errors"
 	"fmt"
 	"text/template"
 
@@ -12,36 +13,53 @@ import (
 type ruleBuilder struct {
 	name              string
 	starts, goals     []korrel8r.Class
-	query, constraint *template.Template
+	query, constraint *ruleTemplate
+	cost              float64
 	engine            *engine.Engine
 }
 
 func newRuleBuilder(r *Rule, e *engine.Engine) (*ruleBuilder, error) {
 	var (
 		err error
-		rb  = &ruleBuilder{name: r.Name, engine: e}
+		rb  = &ruleBuilder{name: r.Name, cost: r.Cost, engine: e}
 	)
 	if rb.name == "" {
 		rb.name = fmt.Sprintf("%v_to_%v", r.Start, r.Goal)
 	}
 	if rb.starts, err = rb.expand(&r.Start, "start"); err != nil {
-		return nil, fmt.Errorf("expanding start of %v: %w", r.Name, err)
+		return nil, rb.fieldError("start", err)
 	}
 	if rb.goals, err = rb.expand(&r.Goal, "goal"); err != nil {
-		return nil, fmt.Errorf("expanding goal of %v: %w", r.Name, err)
+		return nil, rb.fieldError("goal", err)
 	}
 	if r.Result.Query == "" {
-		return nil, fmt.Errorf("template is empty: %v.result.query", rb.name)
+		return nil, rb.fieldError("result.query", errors.New("template is empty"))
 	}
 	if rb.query, err = rb.newTemplate(r.Result.Query, ""); err != nil {
-		return nil, err
+		return nil, rb.fieldError("result.query", err)
 	}
-	if rb.constraint, err = rb.newTemplate(r.Result.Constraint, "-constraint"); err != nil {
-		return nil, err
+	if r.Result.Constraint != "" {
+		if rb.constraint, err = rb.newTemplate(r.Result.Constraint, "-constraint"); err != nil {
+			return nil, rb.fieldError("result.constraint", err)
+		}
 	}
 	return rb, nil
 }
 
+// fieldError is an error in a field of a template rule.
+type fieldError struct {
+	rule  string
+	field string // Path to the field, for example "result.query".
+	err   error
+}
+
+func (e *fieldError) Error() string { return fmt.Sprintf("rule %v: %v: %v", e.rule, e.field, e.err) }
+func (e *fieldError) Unwrap() error { return e.err }
+
+func (rb *ruleBuilder) fieldError(field string, err error) error {
+	return &fieldError{rule: rb.name, field: field, err: err}
+}
+
 func (rb *ruleBuilder) expand(spec *ClassSpec, what string) (classes []korrel8r.Class, err error) {
 	domain, err := rb.engine.DomainErr(spec.Domain)
 	if err != nil {
@@ -61,12 +79,16 @@ func (rb *ruleBuilder) expand(spec *ClassSpec, what string) (classes []korrel8r.
 	return list.List, nil
 }
 
-func (rb *ruleBuilder) newTemplate(text, suffix string) (*template.Template, error) {
-	return template.New(rb.name + suffix).
+func (rb *ruleBuilder) newTemplate(text, suffix string) (*ruleTemplate, error) {
+	t, err := template.New(rb.name + suffix).
 		Option("missingkey=error").
 		Funcs(Funcs).
 		Funcs(rb.engine.TemplateFuncs()).
 		Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &ruleTemplate{Template: t}, nil
 }
 
 func (rb *ruleBuilder) rules() (rules []korrel8r.Rule, err error) {
@@ -77,6 +99,7 @@ func (rb *ruleBuilder) rules() (rules []korrel8r.Rule, err error) {
 				goal:       goal,
 				query:      rb.query,
 				constraint: rb.constraint,
+				cost:       rb.cost,
 			})
 		}
 	}
@@ -1,7 +1,10 @@
 package templaterule
 
 import (
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/korrel8r/korrel8r/internal/pkg/test/mock"
 	"github.com/korrel8r/korrel8r/pkg/engine"
@@ -83,3 +86,125 @@ result: {query: dummy, class: dummy}
 		})
 	}
 }
+
+func TestRule_Cost(t *testing.T) {
+	e := engine.New()
+	e.AddDomain(mock.Domain("foo a b"), nil)
+	var rule Rule
+	require.NoError(t, yaml.Unmarshal([]byte(`
+start:  {domain: "foo", classes: [a]}
+goal:   {domain: "foo", classes: [b]}
+cost: 2.5
+result: {query: dummy}
+`), &rule))
+	rules, err := rule.Rules(e)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, 2.5, e.Cost(rules[0]))
+}
+
+func TestRule_Constraint(t *testing.T) {
+	e := engine.New()
+	e.AddDomain(mock.Domain("foo a b"), nil)
+	var rule Rule
+	require.NoError(t, yaml.Unmarshal([]byte(`
+start:  {domain: "foo", classes: [a]}
+goal:   {domain: "foo", classes: [b]}
+result:
+  query: dummy
+  constraint: |-
+    {{if .}}{"start": {{json .}}, "limit": 10}{{end}}
+`), &rule))
+	rules, err := rule.Rules(e)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	r := rules[0].(engine.Constrainer)
+
+	t1, t2 := time.Unix(1, 0).UTC(), time.Unix(2, 0).UTC()
+	limit := uint(5)
+	c, err := r.Constraint(t2, &korrel8r.Constraint{Start: &t1, Limit: &limit})
+	require.NoError(t, err)
+	assert.Equal(t, &korrel8r.Constraint{Start: &t2, Limit: &limit}, c)
+
+	// Blank template result, no change.
+	c = &korrel8r.Constraint{Start: &t1}
+	got, err := r.Constraint(nil, c)
+	require.NoError(t, err)
+	assert.Same(t, c, got)
+}
+
+func TestRule_ApplyAll(t *testing.T) {
+	e := engine.New()
+	e.AddDomain(mock.Domain("foo a b c"), nil)
+	var rule Rule
+	require.NoError(t, yaml.Unmarshal([]byte(`
+start:  {domain: "foo", classes: [a]}
+goal:   {domain: "foo", classes: [b, c]}
+result:
+  query: |-
+    {{range .}}
+    ---
+    "foo/{{.}}:x"
+    {{end}}
+`), &rule))
+	rules, err := rule.Rules(e)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	r := rules[0].(engine.MultiApplier)
+	require.Equal(t, "foo/b", korrel8r.ClassName(rules[0].Goal()))
+
+	// Queries for other goals are ignored.
+	queries, err := r.ApplyAll([]string{"b", "c", "b"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []korrel8r.Query{mock.Query("foo/b:x"), mock.Query("foo/b:x")}, queries)
+	_, err = rules[0].Apply([]string{"b", "c", "b"}, nil)
+	assert.EqualError(t, err, "apply: expected one query, got 2")
+	q, err := rules[0].Apply([]string{"b"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, mock.Query("foo/b:x"), q)
+
+	_, err = r.ApplyAll([]string{"c"}, nil)
+	assert.ErrorIs(t, err, korrel8r.ErrWrongGoal)
+	_, err = r.ApplyAll([]string{}, nil)
+	assert.EqualError(t, err, "apply: blank query")
+}
+
+// slowObject gives other goroutines time to run during template execution.
+type slowObject int
+
+func (o slowObject) Slow() int { time.Sleep(time.Millisecond); return int(o) }
+
+func TestRule_ApplyConcurrent(t *testing.T) {
+	e := engine.New()
+	e.AddDomain(mock.Domain("foo a b c"), nil)
+	var rule Rule
+	require.NoError(t, yaml.Unmarshal([]byte(`
+start:  {domain: "foo", classes: [a]}
+goal:   {domain: "foo", classes: [b, c]}
+result:
+  query: '"foo/b:{{.Slow}}-{{(constraint).Limit}}"'
+  constraint: '{{if .Slow}}{{end}}{"limit": {{(constraint).Limit}}}'
+`), &rule))
+	rules, err := rule.Rules(e)
+	require.NoError(t, err)
+	// Rules from the same template, applied concurrently with different constraints.
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		i, r := i, rules[i%len(rules)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limit := uint(i)
+			q, err := r.Apply(slowObject(i), &korrel8r.Constraint{Limit: &limit})
+			if korrel8r.ClassName(r.Goal()) == "foo/b" {
+				assert.NoError(t, err)
+				assert.Equal(t, mock.Query(fmt.Sprintf("foo/b:%v-%v", i, i)), q)
+			}
+			c, err := r.(engine.Constrainer).Constraint(slowObject(i), &korrel8r.Constraint{Limit: &limit})
+			if assert.NoError(t, err) {
+				assert.Equal(t, limit, *c.Limit)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,153 @@
+package templaterule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/korrel8r/korrel8r/pkg/engine"
+	"github.com/korrel8r/korrel8r/pkg/korrel8r"
+	"go.uber.org/multierr"
+	"golang.org/x/exp/slices"
+)
+
+// Test is a test case for a rule, it can be included in the `tests` section of a rule file.
+// A test applies the rule to a start object without contacting any stores.
+//
+// For example:
+//
+//	tests:
+//	  - rule: PodToLogs
+//	    start: k8s/Pod
+//	    object: {metadata: {namespace: ns, name: x}}
+//	    query: {LogType: application, LogQL: '{kubernetes_namespace_name="ns",kubernetes_pod_name="x"} | json'}
+type Test struct {
+	// Name of the test, optional. Defaults to the rule name.
+	Name string `json:"name,omitempty"`
+	// Rule is the name of the template rule to test.
+	Rule string `json:"rule"`
+	// Start is the full class name of the start object. Optional if the rule has a single start class.
+	Start string `json:"start,omitempty"`
+	// Goal is the full class name of the goal. Optional, if absent all goals of the rule are tried.
+	Goal string `json:"goal,omitempty"`
+	// Object is the start object, in the JSON or YAML form for the start class.
+	Object json.RawMessage `json:"object"`
+	// Query is the expected query in the JSON or YAML form for the goal domain.
+	// If the rule generates several queries, the test passes if any of them is the expected query.
+	// If absent, the rule is expected not to apply to the start object:
+	// it generates a blank query, or only queries for other goal classes.
+	// Any other error applying the rule fails the test.
+	Query json.RawMessage `json:"query,omitempty"`
+	// Constraint is the expected constraint generated by the rule, optional.
+	Constraint *korrel8r.Constraint `json:"constraint,omitempty"`
+}
+
+func (t *Test) String() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.Rule
+}
+
+// DecodeTests is the same as DecodeTestsFile with an empty file name.
+func DecodeTests(r io.Reader) ([]Test, error) { return DecodeTestsFile("", r) }
+
+// DecodeTestsFile decodes the tests in the named rule file, rules in the file are ignored.
+// Tests that cannot be decoded are skipped, the returned error combines their errors as for DecodeFile.
+func DecodeTestsFile(name string, r io.Reader) ([]Test, error) {
+	rf, err := decodeFile(name, r)
+	if err != nil {
+		return nil, err
+	}
+	return rf.Tests, rf.combine(rf.testErrs)
+}
+
+// Run the test using the rules of engine e, returns an error if the test fails.
+func (t *Test) Run(e *engine.Engine) error {
+	var start, goal korrel8r.Class
+	var err error
+	if t.Start != "" {
+		if start, err = e.Class(t.Start); err != nil {
+			return err
+		}
+	}
+	if t.Goal != "" {
+		if goal, err = e.Class(t.Goal); err != nil {
+			return err
+		}
+	}
+	var rules []korrel8r.Rule
+	for _, r := range e.Rules() {
+		if r.String() == t.Rule && (start == nil || r.Start() == start) && (goal == nil || r.Goal() == goal) {
+			rules = append(rules, r)
+		}
+	}
+	if len(rules) == 0 {
+		return fmt.Errorf("rule not found: %v", t.Rule)
+	}
+	start = rules[0].Start()
+	if slices.IndexFunc(rules, func(r korrel8r.Rule) bool { return r.Start() != start }) >= 0 {
+		return fmt.Errorf("rule %v has several start classes, test must set start", t.Rule)
+	}
+	object, err := korrel8r.UnmarshalObject(start, t.Object)
+	if err != nil {
+		return fmt.Errorf("invalid start object: %w", err)
+	}
+	var (
+		queries  []string
+		failures error
+		found    korrel8r.Rule // Rule that generated the expected query.
+	)
+	for _, r := range rules {
+		got, err := engine.ApplyAll(r, object, nil)
+		if errors.Is(err, korrel8r.ErrWrongGoal) || errors.Is(err, errBlankQuery) {
+			continue // Does not apply.
+		}
+		if err != nil {
+			failures = multierr.Append(failures, err)
+			continue
+		}
+		for _, q := range got {
+			queries = append(queries, korrel8r.JSONString(q))
+		}
+		if len(t.Query) == 0 || found != nil {
+			continue
+		}
+		want, err := r.Goal().Domain().UnmarshalQuery(t.Query)
+		if err == nil && slices.Contains(queries[len(queries)-len(got):], korrel8r.JSONString(want)) {
+			found = r
+		}
+	}
+	switch {
+	case failures != nil:
+		return fmt.Errorf("rule failed: %w", failures)
+	case len(t.Query) == 0 && len(queries) == 0:
+		return nil // Expected not to apply.
+	case len(t.Query) == 0:
+		return fmt.Errorf("expected rule not to apply, got: %v", queries)
+	case found != nil && t.Constraint != nil:
+		return t.checkConstraint(found, object)
+	case found != nil:
+		return nil
+	case len(queries) == 0:
+		return errors.New("rule did not apply")
+	default:
+		return fmt.Errorf("expected query %v, got: %v", string(t.Query), queries)
+	}
+}
+
+func (t *Test) checkConstraint(r korrel8r.Rule, object korrel8r.Object) error {
+	cr, ok := r.(engine.Constrainer)
+	if !ok {
+		return fmt.Errorf("rule %v does not generate constraints", t.Rule)
+	}
+	c, err := cr.Constraint(object, nil)
+	if err != nil {
+		return err
+	}
+	if got, want := korrel8r.JSONString(c), korrel8r.JSONString(t.Constraint); got != want {
+		return fmt.Errorf("expected constraint %v, got: %v", want, got)
+	}
+	return nil
+}
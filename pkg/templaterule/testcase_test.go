@@ -0,0 +1,61 @@
+package templaterule
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/korrel8r/korrel8r/internal/pkg/test/mock"
+	"github.com/korrel8r/korrel8r/pkg/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTest_Run(t *testing.T) {
+	e := engine.New()
+	e.AddDomain(mock.Domain("foo a b c"), nil)
+	rules := `
+rules:
+  - name:   wild
+    start:  {domain: foo, classes: [a]}
+    goal:   {domain: foo, classes: [b, c]}
+    result: {query: '{{if eq . "foo/a:b"}}"foo/b:x"{{else if eq . "foo/a:c"}}"foo/c:x"{{end}}'}
+  - name:   multi
+    start:  {domain: foo, classes: [a]}
+    goal:   {domain: foo, classes: [b]}
+    result: {query: "\"foo/b:1\"\n---\n\"foo/b:2\"\n"}
+  - name:   broken
+    start:  {domain: foo, classes: [a]}
+    goal:   {domain: foo, classes: [b]}
+    result: {query: '{{if eq . "foo/a:x"}}{{.NoSuchField}}{{else}}"foo/b:1"{{end}}'}
+tests:
+  - {name: pass-b, rule: wild, object: "foo/a:b", query: "foo/b:x"}
+  - {name: pass-c, rule: wild, object: "foo/a:c", query: "foo/c:x"}
+  - {name: pass-no-apply, rule: wild, object: "foo/a:x"}
+  - {name: fail-query, rule: wild, object: "foo/a:b", query: "foo/b:y"}
+  - {name: fail-no-apply, rule: wild, object: "foo/a:b"}
+  - {name: fail-apply, rule: wild, object: "foo/a:x", query: "foo/b:x"}
+  - {name: fail-rule, rule: nosuchrule, object: "foo/a:x"}
+  - {name: pass-multi, rule: multi, object: "foo/a:x", query: "foo/b:2"}
+  - {name: fail-multi, rule: multi, object: "foo/a:x", query: "foo/b:3"}
+  - {name: pass-broken, rule: broken, object: "foo/a:y", query: "foo/b:1"}
+  - {name: fail-broken, rule: broken, object: "foo/a:x"}
+`
+	require.NoError(t, Decode(strings.NewReader(rules), e))
+	tests, err := DecodeTests(strings.NewReader(rules))
+	require.NoError(t, err)
+	require.Len(t, tests, 11)
+	for _, test := range tests {
+		test := test
+		t.Run(test.String(), func(t *testing.T) {
+			err := test.Run(e)
+			if strings.HasPrefix(test.Name, "pass") {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+			if test.Name == "fail-broken" {
+				assert.ErrorContains(t, err, "NoSuchField")
+			}
+		})
+	}
+}
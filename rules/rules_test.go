@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/korrel8r/korrel8r/internal/pkg/test"
 	"github.com/korrel8r/korrel8r/pkg/domains/alert"
@@ -24,6 +25,7 @@ import (
 	"github.com/korrel8r/korrel8r/pkg/unique"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slices"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta/testrestmapper"
@@ -47,22 +49,33 @@ func setup(t *testing.T) *engine.Engine {
 		f, err := os.Open(name)
 		require.NoError(t, err)
 		defer f.Close()
-		require.NoError(t, templaterule.Decode(f, e), "decoding file %v", name)
+		require.NoError(t, templaterule.DecodeFile(name, f, e))
 	}
 	return e
 }
 
-func testTraverse(t *testing.T, e *engine.Engine, start, goal korrel8r.Class, starters []korrel8r.Object, wantQuery korrel8r.Query) {
+// testTraverse follows the shortest paths from start to goal and checks the goal query.
+// Store failures for unsupported queries are ignored, the fake client cannot run all valid queries.
+// For example it can't do multi-field selectors, which are valid on a real cluster.
+func testTraverse(t *testing.T, e *engine.Engine, start, goal korrel8r.Class, starters []korrel8r.Object, wantQuery korrel8r.Query, unsupported ...korrel8r.Query) {
 	t.Helper()
 	paths := e.Graph().ShortestPaths(start, goal)
 	paths.NodeFor(start).Result.Append(starters...)
-	f := e.Follower(context.Background())
+	f := e.Follower(context.Background(), nil)
 	assert.NoError(t, paths.Traverse(f.Traverse))
-	assert.NoError(t, f.Err)
+	for _, failure := range f.Failures {
+		if failure.Kind == engine.StoreFailed && slices.IndexFunc(unsupported, func(q korrel8r.Query) bool {
+			return korrel8r.JSONString(q) == korrel8r.JSONString(failure.Query)
+		}) >= 0 {
+			continue
+		}
+		t.Errorf("unexpected failure: %v", failure)
+	}
 	n := paths.NodeFor(goal)
-	want := graph.QueryCounts{}
-	want.Put(wantQuery, 0)
-	assert.Equal(t, want, n.QueryCounts)
+	assert.Len(t, n.QueryCounts, 1)
+	qc, ok := n.QueryCounts.Get(wantQuery)
+	assert.True(t, ok, "missing query: %v", korrel8r.JSONString(wantQuery))
+	assert.Equal(t, 0, qc.Count)
 }
 
 func TestPodToLogs(t *testing.T) {
@@ -158,7 +171,7 @@ func TestK8sEvent(t *testing.T) {
 			client.MatchingFields{
 				"involvedObject.apiVersion": "v1", "involvedObject.kind": "Pod",
 				"involvedObject.name": "foo", "involvedObject.namespace": "aNamespace"})
-		testTraverse(t, e, k8s.ClassOf(pod), k8s.ClassOf(event), []korrel8r.Object{pod}, want)
+		testTraverse(t, e, k8s.ClassOf(pod), k8s.ClassOf(event), []korrel8r.Object{pod}, want, want)
 	})
 	t.Run("EventToPod", func(t *testing.T) {
 		testTraverse(t, e, k8s.ClassOf(event), k8s.ClassOf(pod), []korrel8r.Object{event},
@@ -173,3 +186,105 @@ func TestK8sAllToMetric(t *testing.T) {
 	want := &metric.Query{PromQL: "{ namespace=\"aNamespace\", pod=\"foo\" }"}
 	testTraverse(t, e, k8s.ClassOf(pod), metric.Class{}, []korrel8r.Object{pod}, want)
 }
+
+func TestAlertToWorkload(t *testing.T) {
+	e := setup(t)
+	for _, x := range []struct {
+		label string
+		goal  korrel8r.Class
+	}{
+		{"pod", k8s.Domain.Class("Pod")},
+		{"deployment", k8s.Domain.Class("Deployment.apps")},
+		{"statefulset", k8s.Domain.Class("StatefulSet.apps")},
+		{"daemonset", k8s.Domain.Class("DaemonSet.apps")},
+	} {
+		t.Run(x.label, func(t *testing.T) {
+			a := &alert.Object{Labels: map[string]string{"namespace": "ns", x.label: "x"}}
+			want, err := k8s.Domain.UnmarshalQuery([]byte(fmt.Sprintf(`{"Group": %q, "Version": "v1", "Kind": %q, "Namespace": "ns", "Name": "x"}`,
+				x.goal.(k8s.Class).Group, x.goal.(k8s.Class).Kind)))
+			require.NoError(t, err)
+			testTraverse(t, e, alert.Domain.Class("alert"), x.goal, []korrel8r.Object{a}, want)
+
+			// Rules for the other workload classes do not apply, and do not fail.
+			f := e.Follower(context.Background(), nil)
+			paths := e.Graph().Neighbours(alert.Domain.Class("alert"), 1, nil)
+			paths.NodeFor(alert.Domain.Class("alert")).Result.Append(a)
+			assert.NoError(t, paths.Traverse(f.Traverse))
+			assert.NoError(t, f.Err())
+			paths.EachNode(func(n *graph.Node) {
+				if n.Class.Domain() == k8s.Domain {
+					assert.Equal(t, n.Class == x.goal, len(n.QueryCounts) == 1, "%v", korrel8r.ClassName(n.Class))
+				}
+			})
+		})
+	}
+}
+
+func TestAlertToWorkload_Labels(t *testing.T) {
+	e := setup(t)
+	// One query for each workload label.
+	a := &alert.Object{Labels: map[string]string{"namespace": "ns", "pod": "x", "deployment": "y"}}
+	f := e.Follower(context.Background(), nil)
+	paths := e.Graph().Neighbours(alert.Domain.Class("alert"), 1, nil)
+	paths.NodeFor(alert.Domain.Class("alert")).Result.Append(a)
+	assert.NoError(t, paths.Traverse(f.Traverse))
+	assert.NoError(t, f.Err())
+	var got []string
+	paths.EachNode(func(n *graph.Node) {
+		for q := range n.QueryCounts {
+			got = append(got, q)
+		}
+	})
+	assert.ElementsMatch(t, []string{
+		`{"Group":"","Version":"v1","Kind":"Pod","Namespace":"ns","Name":"x","Labels":null,"Fields":null}`,
+		`{"Group":"apps","Version":"v1","Kind":"Deployment","Namespace":"ns","Name":"y","Labels":null,"Fields":null}`,
+	}, got)
+}
+
+func TestAlertToWorkload_Constraint(t *testing.T) {
+	e := setup(t)
+	var rule engine.Constrainer
+	for _, r := range e.Rules() {
+		if r.String() == "AlertToWorkload" && r.Goal() == k8s.Domain.Class("Pod") {
+			rule = r.(engine.Constrainer)
+		}
+	}
+	require.NotNil(t, rule)
+	start := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	before, end := start.Add(-10*time.Minute), start.Add(time.Hour)
+
+	c, err := rule.Constraint(&alert.Object{ActiveAt: start}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, &korrel8r.Constraint{Start: &before}, c)
+
+	c, err = rule.Constraint(&alert.Object{StartsAt: start, EndsAt: end}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, &korrel8r.Constraint{Start: &before, End: &end}, c)
+
+	// Intersect with the caller's constraint.
+	later := start.Add(time.Minute)
+	c, err = rule.Constraint(&alert.Object{StartsAt: start, EndsAt: end}, &korrel8r.Constraint{Start: &later})
+	require.NoError(t, err)
+	assert.Equal(t, &korrel8r.Constraint{Start: &later, End: &end}, c)
+
+	c, err = rule.Constraint(&alert.Object{}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, c)
+}
+
+func TestRuleFileTests(t *testing.T) {
+	e := setup(t)
+	names, err := filepath.Glob("*.yaml")
+	require.NoError(t, err)
+	for _, name := range names {
+		f, err := os.Open(name)
+		require.NoError(t, err)
+		defer f.Close()
+		tests, err := templaterule.DecodeTestsFile(name, f)
+		require.NoError(t, err)
+		for _, test := range tests {
+			test := test
+			t.Run(name+"/"+test.String(), func(t *testing.T) { assert.NoError(t, test.Run(e)) })
+		}
+	}
+}